@@ -0,0 +1,277 @@
+// Package api is a read-only REST API over a message catalog for other
+// systems, such as ticketing tools, CMDBs and chat bots, that need
+// message metadata:
+//
+//	GET  /messages              entries, filtered by ?severity=, ?prefix= and ?q=
+//	GET  /messages/{id}         one entry; severity letters are accepted
+//	POST /messages/{id}/render  fill the placeholders from a JSON context
+//	GET  /openapi.json          the OpenAPI description of the above
+//
+// Text and help are negotiated from Accept-Language among the locale
+// catalogs. GET responses carry ETags and answer If-None-Match with 304.
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/render"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+// maxBody bounds the size of a render request
+const maxBody = 1 << 20
+
+// Handler serves the API. Paths are relative, so it can be mounted under
+// a prefix with http.StripPrefix.
+type Handler struct {
+	// Catalog is the base catalog
+	Catalog catalog.Catalog
+	// DefaultLocale is the language of Catalog (default: "en")
+	DefaultLocale string
+
+	// locales holds Catalog localized for each added language tag
+	locales map[string]catalog.Catalog
+}
+
+// NewHandler creates an API for cat
+func NewHandler(cat catalog.Catalog) *Handler {
+	return &Handler{Catalog: cat}
+}
+
+// entry is a catalog entry as the API returns it
+type entry struct {
+	catalog.CatalogEntry
+	Cause        string   `json:"cause,omitempty"`
+	Recovery     string   `json:"recovery,omitempty"`
+	Placeholders []string `json:"placeholders,omitempty"`
+	Locale       string   `json:"locale"`
+}
+
+// rendered is the answer to a render request
+type rendered struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Text     string `json:"text"`
+	Template string `json:"template"`
+	// Missing lists placeholders the context did not fill
+	Missing []string `json:"missing,omitempty"`
+	Locale  string   `json:"locale"`
+}
+
+// renderRequest is the body of a render request
+type renderRequest struct {
+	Context map[string]string `json:"context"`
+}
+
+// ServeHTTP routes API requests
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	switch {
+	case path == "openapi.json":
+		if allow(w, r, http.MethodGet, http.MethodHead) {
+			writeCached(w, r, "application/json", openAPI)
+		}
+	case path == "messages":
+		if allow(w, r, http.MethodGet, http.MethodHead) {
+			h.list(w, r)
+		}
+	case len(parts) == 2 && parts[0] == "messages":
+		if allow(w, r, http.MethodGet, http.MethodHead) {
+			h.get(w, r, parts[1])
+		}
+	case len(parts) == 3 && parts[0] == "messages" && parts[2] == "render":
+		if allow(w, r, http.MethodPost) {
+			h.render(w, r, parts[1])
+		}
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such resource: /%s", path))
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	cat, locale := h.negotiate(w, r)
+	q := r.URL.Query()
+	severity := strings.ToUpper(q.Get("severity"))
+	prefix := strings.ToUpper(q.Get("prefix"))
+	terms := strings.Fields(strings.ToLower(q.Get("q")))
+
+	entries := []entry{}
+	for _, e := range cat {
+		if severity != "" && e.Severity != severity {
+			continue
+		}
+		if !strings.HasPrefix(e.ID, prefix) || !matches(e, terms) {
+			continue
+		}
+		entries = append(entries, newEntry(e, locale))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	writeJSON(w, r, entries)
+}
+
+// matches reports whether every term occurs in the ID, text or help
+func matches(e catalog.CatalogEntry, terms []string) bool {
+	haystack := strings.ToLower(e.ID + " " + e.Text + " " + e.Help)
+	for _, t := range terms {
+		if !strings.Contains(haystack, t) {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, id string) {
+	cat, locale := h.negotiate(w, r)
+	e, ok := cat.Lookup(strings.ToUpper(id))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown message ID %q", id))
+		return
+	}
+	writeJSON(w, r, newEntry(e, locale))
+}
+
+// render fills the placeholders of a message. With ?layout=simple, box or
+// plain the response is the message in that layout as plain text.
+func (h *Handler) render(w http.ResponseWriter, r *http.Request, id string) {
+	cat, locale := h.negotiate(w, r)
+	e, ok := cat.Lookup(strings.ToUpper(id))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown message ID %q", id))
+		return
+	}
+
+	var req renderRequest
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err == nil && len(body) > maxBody {
+		err = errors.New("request body too large")
+	}
+	if err == nil && len(bytes.TrimSpace(body)) > 0 {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	msg := cat.New(e.ID, req.Context)
+	var renderer render.Renderer
+	switch layout := r.URL.Query().Get("layout"); layout {
+	case "":
+	case "simple":
+		renderer = render.Simple{Options: render.Options{DisableColors: true}}
+	case "box":
+		renderer = render.Box{Options: render.Options{DisableColors: true}}
+	case "plain":
+		renderer = render.Plain{Options: render.Options{DisableColors: true}}
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown layout %q", layout))
+		return
+	}
+	if renderer != nil {
+		var buf bytes.Buffer
+		if err := renderer.Render(&buf, msg); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write(buf.Bytes())
+		return
+	}
+
+	var missing []string
+	for _, name := range message.Placeholders(e.Text) {
+		if _, ok := req.Context[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	writeJSON(w, r, rendered{
+		ID:       e.ID,
+		Severity: e.Severity,
+		Text:     msg.Render(),
+		Template: e.Text,
+		Missing:  missing,
+		Locale:   locale,
+	})
+}
+
+func newEntry(e catalog.CatalogEntry, locale string) entry {
+	return entry{
+		CatalogEntry: e,
+		Cause:        e.Cause(),
+		Recovery:     e.Recovery(),
+		Placeholders: message.Placeholders(e.Text),
+		Locale:       locale,
+	}
+}
+
+// allow reports whether r uses one of methods and answers 405 otherwise
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+// writeJSON writes v with an ETag, or 304 when it matches If-None-Match
+func writeJSON(w http.ResponseWriter, r *http.Request, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeCached(w, r, "application/json", append(data, '\n'))
+}
+
+// writeCached writes data with a strong ETag derived from its content.
+// Only GET and HEAD are answered with 304.
+func writeCached(w http.ResponseWriter, r *http.Request, contentType string, data []byte) {
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", contentType)
+	if (r.Method == http.MethodGet || r.Method == http.MethodHead) && etagMatch(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+// etagMatch reports whether an If-None-Match header matches etag. Weak
+// validators compare equal to strong ones, as RFC 9110 asks for GET.
+func etagMatch(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+func newTestHandler() *Handler {
+	h := NewHandler(catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV001", Severity: "INFO", Text: "Server starting on port {port}", Help: "Cause: Startup. Recovery: None required."},
+		{ID: "SRV002", Severity: "ERROR", Text: "Failed to bind to port {port} on {host}", Help: "Cause: Port in use. Recovery: Free the port.", Replies: []string{"retry"}},
+		{ID: "DB001", Severity: "WARN", Text: "Slow query", Help: "Cause: Missing index. Recovery: Add one."},
+	}))
+	h.AddLocale("de", catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Text: "Port {port} auf {host} nicht verfügbar", Help: "Cause: Port belegt. Recovery: Port freigeben."},
+	}))
+	h.AddLocale("pt-BR", catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Text: "Falha ao usar a porta {port} em {host}"},
+	}))
+	return h
+}
+
+func serve(h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAcceptLanguage(t *testing.T) {
+	h := newTestHandler()
+	for _, tc := range []struct {
+		header, locale, text string
+	}{
+		{"", "en", "Failed to bind to port {port} on {host}"},
+		{"de", "de", "Port {port} auf {host} nicht verfügbar"},
+		{"de-AT, en;q=0.5", "de", "Port {port} auf {host} nicht verfügbar"},
+		{"fr, pt-br;q=0.8, de;q=0.7", "pt-BR", "Falha ao usar a porta {port} em {host}"},
+		{"fr, ja", "en", "Failed to bind to port {port} on {host}"},
+		{"de;q=0, *", "en", "Failed to bind to port {port} on {host}"},
+		{"de;q=bogus, pt", "en", "Failed to bind to port {port} on {host}"},
+	} {
+		rec := serve(h, http.MethodGet, "/messages/SRV002", "", map[string]string{"Accept-Language": tc.header})
+		var e entry
+		if err := json.Unmarshal(rec.Body.Bytes(), &e); err != nil {
+			t.Fatalf("%q: %v: %s", tc.header, err, rec.Body)
+		}
+		if e.Locale != tc.locale || rec.Header().Get("Content-Language") != tc.locale || e.Text != tc.text {
+			t.Errorf("%q: got %s (Content-Language %s) %q, want %s %q", tc.header, e.Locale, rec.Header().Get("Content-Language"), e.Text, tc.locale, tc.text)
+		}
+		if rec.Header().Get("Vary") != "Accept-Language" {
+			t.Errorf("%q: Vary = %q", tc.header, rec.Header().Get("Vary"))
+		}
+	}
+
+	// Translations without help, and entries a locale leaves out, keep
+	// the base wording
+	rec := serve(h, http.MethodGet, "/messages/SRV002", "", map[string]string{"Accept-Language": "pt-BR"})
+	var e entry
+	json.Unmarshal(rec.Body.Bytes(), &e)
+	if e.Cause != "Port in use." || e.Severity != "ERROR" || len(e.Replies) != 1 {
+		t.Errorf("pt-BR entry lost base fields: %+v", e)
+	}
+	rec = serve(h, http.MethodGet, "/messages/DB001", "", map[string]string{"Accept-Language": "de"})
+	e = entry{}
+	json.Unmarshal(rec.Body.Bytes(), &e)
+	if e.Locale != "de" || e.Text != "Slow query" {
+		t.Errorf("untranslated entry in de: %+v", e)
+	}
+}
+
+func TestDefaultLocale(t *testing.T) {
+	h := NewHandler(catalog.FromEntries([]catalog.CatalogEntry{{ID: "A001", Severity: "INFO", Text: "a"}}))
+	h.DefaultLocale = "fr"
+	h.AddLocale("de", catalog.FromEntries([]catalog.CatalogEntry{{ID: "A001", Text: "de a"}}))
+	rec := serve(h, http.MethodGet, "/messages/A001", "", map[string]string{"Accept-Language": "fr"})
+	if rec.Header().Get("Content-Language") != "fr" || !strings.Contains(rec.Body.String(), `"text": "a"`) {
+		t.Errorf("default locale: %s %s", rec.Header().Get("Content-Language"), rec.Body)
+	}
+}
+
+func TestETag(t *testing.T) {
+	h := newTestHandler()
+	for _, target := range []string{"/messages", "/messages/SRV002E", "/openapi.json"} {
+		rec := serve(h, http.MethodGet, target, "", nil)
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: status %d, ETag %q", target, rec.Code, etag)
+		}
+		for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+			rec := serve(h, http.MethodGet, target, "", map[string]string{"If-None-Match": inm})
+			if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+				t.Errorf("%s If-None-Match %s: status %d, %d bytes", target, inm, rec.Code, rec.Body.Len())
+			}
+		}
+		if rec := serve(h, http.MethodGet, target, "", map[string]string{"If-None-Match": `"other"`}); rec.Code != http.StatusOK {
+			t.Errorf("%s with a stale ETag: status %d", target, rec.Code)
+		}
+		if rec := serve(h, http.MethodHead, target, "", nil); rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("ETag") != etag {
+			t.Errorf("HEAD %s: status %d, %d bytes, ETag %q", target, rec.Code, rec.Body.Len(), rec.Header().Get("ETag"))
+		}
+	}
+
+	// Each language is its own representation
+	en := serve(h, http.MethodGet, "/messages/SRV002", "", nil).Header().Get("ETag")
+	de := serve(h, http.MethodGet, "/messages/SRV002", "", map[string]string{"Accept-Language": "de"}).Header().Get("ETag")
+	if en == de {
+		t.Error("en and de share an ETag")
+	}
+}
+
+func TestList(t *testing.T) {
+	h := newTestHandler()
+	for _, tc := range []struct {
+		query string
+		ids   string
+	}{
+		{"", "DB001,SRV001,SRV002"},
+		{"?severity=error", "SRV002"},
+		{"?prefix=srv", "SRV001,SRV002"},
+		{"?q=PORT+bind", "SRV002"},
+		{"?severity=CRITICAL", ""},
+	} {
+		rec := serve(h, http.MethodGet, "/messages"+tc.query, "", nil)
+		var entries []entry
+		if err := json.Unmarshal(rec.Body.Bytes(), &entries); err != nil || entries == nil {
+			t.Fatalf("%s: %v: %s", tc.query, err, rec.Body)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if got := strings.Join(ids, ","); got != tc.ids {
+			t.Errorf("%s: %s, want %s", tc.query, got, tc.ids)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	h := newTestHandler()
+
+	rec := serve(h, http.MethodPost, "/messages/srv002e/render", `{"context":{"port":"8080"}}`, nil)
+	var got rendered
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("%v: %s", err, rec.Body)
+	}
+	want := rendered{
+		ID: "SRV002", Severity: "ERROR", Locale: "en",
+		Text:     "Failed to bind to port 8080 on {host}",
+		Template: "Failed to bind to port {port} on {host}",
+		Missing:  []string{"host"},
+	}
+	if got.Text != want.Text || got.Template != want.Template || strings.Join(got.Missing, ",") != "host" || got.ID != want.ID || got.Locale != want.Locale {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// An empty body fills nothing
+	rec = serve(h, http.MethodPost, "/messages/SRV001/render", "", map[string]string{"Accept-Language": "de"})
+	got = rendered{}
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if rec.Code != http.StatusOK || got.Text != "Server starting on port {port}" || strings.Join(got.Missing, ",") != "port" {
+		t.Errorf("empty body: %d %+v", rec.Code, got)
+	}
+
+	rec = serve(h, http.MethodPost, "/messages/SRV002/render", `{"context":{"port":"8080","host":"web-1"}}`, map[string]string{"Accept-Language": "de"})
+	got = rendered{}
+	json.Unmarshal(rec.Body.Bytes(), &got)
+	if got.Text != "Port 8080 auf web-1 nicht verfügbar" || got.Missing != nil || got.Locale != "de" {
+		t.Errorf("de: %+v", got)
+	}
+}
+
+func TestRenderLayouts(t *testing.T) {
+	h := newTestHandler()
+	body := `{"context":{"port":"8080","host":"web-1"}}`
+	for _, tc := range []struct {
+		layout string
+		want   []string
+	}{
+		{"simple", []string{"SRV002", "(ERROR): Failed to bind to port 8080 on web-1", "Help: Cause: Port in use."}},
+		{"box", []string{"╭", "SRV002", "Failed to bind to port 8080 on web-1", "1. retry"}},
+		{"plain", []string{"SRV002", "Failed to bind to port 8080 on web-1"}},
+	} {
+		rec := serve(h, http.MethodPost, "/messages/SRV002/render?layout="+tc.layout, body, nil)
+		if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+			t.Errorf("%s: status %d, Content-Type %q", tc.layout, rec.Code, rec.Header().Get("Content-Type"))
+		}
+		out := rec.Body.String()
+		for _, want := range tc.want {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s lacks %q:\n%s", tc.layout, want, out)
+			}
+		}
+		if strings.Contains(out, "\x1b[") {
+			t.Errorf("%s has color codes:\n%q", tc.layout, out)
+		}
+	}
+
+	rec := serve(h, http.MethodPost, "/messages/SRV002/render?layout=fancy", body, nil)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `unknown layout \"fancy\"`) {
+		t.Errorf("unknown layout: %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h := newTestHandler()
+	for _, tc := range []struct {
+		method, target, body string
+		status               int
+	}{
+		{http.MethodGet, "/messages/NOPE001", "", http.StatusNotFound},
+		{http.MethodGet, "/messages/SRV002I", "", http.StatusNotFound},
+		{http.MethodGet, "/elsewhere", "", http.StatusNotFound},
+		{http.MethodPost, "/messages/NOPE001/render", "", http.StatusNotFound},
+		{http.MethodPost, "/messages/SRV002/render", `{"context":`, http.StatusBadRequest},
+		{http.MethodPost, "/messages/SRV002/render", `{"context":{"port":8080}}`, http.StatusBadRequest},
+		{http.MethodPost, "/messages/SRV002/render", strings.Repeat(" ", maxBody+1), http.StatusBadRequest},
+		{http.MethodDelete, "/messages/SRV002", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/messages/SRV002/render", "", http.StatusMethodNotAllowed},
+	} {
+		rec := serve(h, tc.method, tc.target, tc.body, nil)
+		var body map[string]string
+		if rec.Code != tc.status || json.Unmarshal(rec.Body.Bytes(), &body) != nil || body["error"] == "" {
+			t.Errorf("%s %s: %d %s, want %d with an error", tc.method, tc.target, rec.Code, rec.Body, tc.status)
+		}
+	}
+	if rec := serve(h, http.MethodPut, "/messages", "", nil); rec.Header().Get("Allow") != "GET, HEAD" {
+		t.Errorf("Allow = %q", rec.Header().Get("Allow"))
+	}
+}
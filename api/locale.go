@@ -0,0 +1,93 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+// AddLocale serves the catalog in the language tag, such as "de" or
+// "pt-BR", with the text and help of translation; see catalog.Localize.
+// The localized catalog is built from Catalog once, here, so set Catalog
+// first.
+func (h *Handler) AddLocale(tag string, translation catalog.Catalog) {
+	if h.locales == nil {
+		h.locales = make(map[string]catalog.Catalog)
+	}
+	h.locales[tag] = catalog.Localize(h.Catalog, translation)
+}
+
+// negotiate picks the catalog for the languages in the request's
+// Accept-Language header and returns it with its language tag
+func (h *Handler) negotiate(w http.ResponseWriter, r *http.Request) (catalog.Catalog, string) {
+	def := h.DefaultLocale
+	if def == "" {
+		def = "en"
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	tag := def
+	if len(h.locales) > 0 {
+		available := make([]string, 0, len(h.locales)+1)
+		available = append(available, def)
+		for t := range h.locales {
+			available = append(available, t)
+		}
+		tag = matchLanguage(r.Header.Get("Accept-Language"), available)
+	}
+	w.Header().Set("Content-Language", tag)
+
+	if cat, ok := h.locales[tag]; ok {
+		return cat, tag
+	}
+	return h.Catalog, tag
+}
+
+// matchLanguage returns the tag in available that best matches an
+// Accept-Language header, or available[0]. A range matches a tag equal to
+// it or to its primary language: "de-AT" matches "de-AT", then "de".
+func matchLanguage(header string, available []string) string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag = strings.TrimSpace(tag); tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			ranges = append(ranges, weighted{tag, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+
+	for _, lr := range ranges {
+		if lr.tag == "*" {
+			return available[0]
+		}
+		for _, t := range available {
+			if strings.EqualFold(t, lr.tag) {
+				return t
+			}
+		}
+		primary, _, _ := strings.Cut(lr.tag, "-")
+		for _, t := range available {
+			if strings.EqualFold(t, primary) {
+				return t
+			}
+		}
+	}
+	return available[0]
+}
@@ -0,0 +1,24 @@
+package catalog
+
+import (
+	"bytes"
+	_ "embed"
+)
+
+//go:embed builtin.yaml
+var builtinYAML []byte
+
+// BuiltinEntries returns the entries of the built-in catalog shipped with
+// the module, so tools work without a copy of builtin.yaml on disk
+func BuiltinEntries() []CatalogEntry {
+	entries, err := decodeYAML(bytes.NewReader(builtinYAML))
+	if err != nil {
+		panic("catalog: invalid builtin.yaml: " + err.Error())
+	}
+	return entries
+}
+
+// Builtin returns the built-in catalog
+func Builtin() Catalog {
+	return FromEntries(BuiltinEntries())
+}
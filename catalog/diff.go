@@ -0,0 +1,125 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// DiffKind says what a Difference is about
+type DiffKind string
+
+// Kinds of Difference
+const (
+	DiffAdded              DiffKind = "added"
+	DiffRemoved            DiffKind = "removed"
+	DiffSeverity           DiffKind = "severity"
+	DiffPlaceholderAdded   DiffKind = "placeholder-added"
+	DiffPlaceholderRemoved DiffKind = "placeholder-removed"
+	DiffPlaceholderRenamed DiffKind = "placeholder-renamed"
+	DiffText               DiffKind = "text"
+	DiffHelp               DiffKind = "help"
+	DiffReplies            DiffKind = "replies"
+	DiffLinks              DiffKind = "links"
+)
+
+// Difference is one change between two versions of a catalog
+type Difference struct {
+	ID   string   `json:"id"`
+	Kind DiffKind `json:"kind"`
+	Old  string   `json:"old,omitempty"`
+	New  string   `json:"new,omitempty"`
+	// Breaking marks changes that can break consumers keying on IDs,
+	// severities or placeholders, such as dashboards and alert rules
+	Breaking bool `json:"breaking"`
+}
+
+// Diff compares two versions of a catalog. Removed IDs, severity changes
+// and removed or renamed placeholders are breaking; added IDs and
+// placeholders and edits to text, help, replies and links are not.
+// Differences are ordered by ID.
+func Diff(from, to Catalog) []Difference {
+	ids := make(map[string]bool)
+	for id := range from {
+		ids[id] = true
+	}
+	for id := range to {
+		ids[id] = true
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	var diffs []Difference
+	for _, id := range sorted {
+		o, inOld := from[id]
+		n, inNew := to[id]
+		switch {
+		case !inOld:
+			diffs = append(diffs, Difference{ID: id, Kind: DiffAdded, New: n.Text})
+		case !inNew:
+			diffs = append(diffs, Difference{ID: id, Kind: DiffRemoved, Old: o.Text, Breaking: true})
+		default:
+			diffs = append(diffs, diffEntry(o, n)...)
+		}
+	}
+	return diffs
+}
+
+func diffEntry(o, n CatalogEntry) []Difference {
+	var diffs []Difference
+	add := func(kind DiffKind, old, new string, breaking bool) {
+		diffs = append(diffs, Difference{ID: o.ID, Kind: kind, Old: old, New: new, Breaking: breaking})
+	}
+
+	if o.Severity != n.Severity {
+		add(DiffSeverity, o.Severity, n.Severity, true)
+	}
+
+	// Removed and added placeholders are paired in order of appearance
+	// and taken to be renamed; the rest were removed or added
+	removed := missing(message.Placeholders(o.Text), message.Placeholders(n.Text))
+	added := missing(message.Placeholders(n.Text), message.Placeholders(o.Text))
+	for len(removed) > 0 && len(added) > 0 {
+		add(DiffPlaceholderRenamed, removed[0], added[0], true)
+		removed, added = removed[1:], added[1:]
+	}
+	for _, p := range removed {
+		add(DiffPlaceholderRemoved, p, "", true)
+	}
+	for _, p := range added {
+		add(DiffPlaceholderAdded, "", p, false)
+	}
+
+	if o.Text != n.Text {
+		add(DiffText, o.Text, n.Text, false)
+	}
+	if o.Help != n.Help {
+		add(DiffHelp, o.Help, n.Help, false)
+	}
+	if strings.Join(o.Replies, "\n") != strings.Join(n.Replies, "\n") {
+		add(DiffReplies, strings.Join(o.Replies, ", "), strings.Join(n.Replies, ", "), false)
+	}
+	if strings.Join(o.Links, "\n") != strings.Join(n.Links, "\n") {
+		add(DiffLinks, strings.Join(o.Links, ", "), strings.Join(n.Links, ", "), false)
+	}
+	return diffs
+}
+
+// missing returns the names in a that are not in b, in order
+func missing(a, b []string) []string {
+	in := make(map[string]bool, len(b))
+	for _, name := range b {
+		in[name] = true
+	}
+	var out []string
+	for _, name := range a {
+		if !in[name] {
+			out = append(out, name)
+		}
+	}
+	return out
+}
@@ -0,0 +1,39 @@
+package catalog
+
+import "testing"
+
+func TestDiffPlaceholders(t *testing.T) {
+	from := FromEntries([]CatalogEntry{
+		{ID: "SRV002", Severity: "ERROR", Text: "Failed to bind {host}:{port} as {user}"},
+		{ID: "SRV003", Severity: "CRITICAL", Text: "Gone"},
+	})
+	to := FromEntries([]CatalogEntry{
+		// {host} and {user} go, {addr} and {uid} and {pid} arrive: the
+		// first two pairs are renames in order of appearance
+		{ID: "SRV002", Severity: "WARN", Text: "Failed to bind {addr}:{port} as {uid} ({pid})"},
+		{ID: "SRV004", Severity: "INFO", Text: "New"},
+	})
+
+	var got []Difference
+	for _, d := range Diff(from, to) {
+		if d.Kind != DiffText {
+			got = append(got, d)
+		}
+	}
+	want := []Difference{
+		{ID: "SRV002", Kind: DiffSeverity, Old: "ERROR", New: "WARN", Breaking: true},
+		{ID: "SRV002", Kind: DiffPlaceholderRenamed, Old: "host", New: "addr", Breaking: true},
+		{ID: "SRV002", Kind: DiffPlaceholderRenamed, Old: "user", New: "uid", Breaking: true},
+		{ID: "SRV002", Kind: DiffPlaceholderAdded, New: "pid"},
+		{ID: "SRV003", Kind: DiffRemoved, Old: "Gone", Breaking: true},
+		{ID: "SRV004", Kind: DiffAdded, New: "New"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d differences, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("difference %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decoder reads the entries of a catalog file in file order
+type Decoder func(r io.Reader) ([]CatalogEntry, error)
+
+// Catalog file formats
+const (
+	FormatYAML      = "yaml"
+	FormatJSON      = "json"
+	FormatJSONLines = "jsonl"
+)
+
+var (
+	formatsMu  sync.RWMutex
+	decoders   = map[string]Decoder{FormatYAML: decodeYAML, FormatJSON: decodeJSON, FormatJSONLines: decodeJSONLines}
+	extensions = map[string]string{
+		".yaml": FormatYAML, ".yml": FormatYAML,
+		".json":  FormatJSON,
+		".jsonl": FormatJSONLines, ".ndjson": FormatJSONLines,
+	}
+)
+
+// RegisterDecoder adds or replaces the decoder for format and selects it
+// for files with the given extensions, such as ".toml"
+func RegisterDecoder(format string, d Decoder, exts ...string) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	decoders[format] = d
+	for _, ext := range exts {
+		extensions[strings.ToLower(ext)] = format
+	}
+}
+
+// FormatOf returns the format for path by its extension. Unknown
+// extensions are read as YAML.
+func FormatOf(path string) string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	if format, ok := extensions[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return FormatYAML
+}
+
+// ReadEntries reads the entries of a catalog in format from r, in order
+// and keeping duplicates
+func ReadEntries(r io.Reader, format string) ([]CatalogEntry, error) {
+	formatsMu.RLock()
+	d, ok := decoders[format]
+	formatsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("catalog: unknown format %q", format)
+	}
+	return d(r)
+}
+
+// LoadReader reads a catalog in format from r
+func LoadReader(r io.Reader, format string) (Catalog, error) {
+	entries, err := ReadEntries(r, format)
+	if err != nil {
+		return nil, err
+	}
+	return FromEntries(entries), nil
+}
+
+// decodeYAML reads a YAML list of entries
+func decodeYAML(r io.Reader) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	if err := yaml.NewDecoder(r).Decode(&entries); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// decodeJSON reads a JSON array of entries, which must be all the input
+func decodeJSON(r io.Reader) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&entries); err != nil && err != io.EOF {
+		return nil, err
+	}
+	// More misses a stray closing bracket, so look for any token at all
+	end := dec.InputOffset()
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the entry array at offset %d", end)
+	}
+	return entries, nil
+}
+
+// decodeJSONLines reads one JSON entry per line; blank lines are skipped
+func decodeJSONLines(r io.Reader) ([]CatalogEntry, error) {
+	var entries []CatalogEntry
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; lines.Scan(); n++ {
+		line := bytes.TrimSpace(lines.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var e CatalogEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, lines.Err()
+}
+
+//go:embed catalog.schema.json
+var schema []byte
+
+// JSONSchema returns the JSON Schema of a catalog file, for editors and
+// CI checks. It applies to YAML and JSON catalogs alike.
+func JSONSchema() []byte {
+	return append([]byte(nil), schema...)
+}
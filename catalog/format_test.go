@@ -0,0 +1,129 @@
+package catalog
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadEntries(t *testing.T) {
+	for _, tc := range []struct {
+		name, format, input string
+		ids                 string
+		err                 string
+	}{
+		{"yaml", FormatYAML, "- id: SRV002\n  severity: ERROR\n  text: a\n- id: SRV001\n  text: b\n- id: SRV002\n  text: c\n", "SRV002,SRV001,SRV002", ""},
+		{"empty yaml", FormatYAML, "", "", ""},
+		{"bad yaml", FormatYAML, "- id: [", "", "yaml"},
+		{"json", FormatJSON, `[{"id":"SRV002","severity":"ERROR","text":"a"},{"id":"SRV001"}]`, "SRV002,SRV001", ""},
+		{"json with trailing space", FormatJSON, "[{\"id\":\"SRV001\"}]\n\n", "SRV001", ""},
+		{"empty json", FormatJSON, "", "", ""},
+		{"json not an array", FormatJSON, `{"id":"SRV001"}`, "", "cannot unmarshal"},
+		{"json with a second value", FormatJSON, `[{"id":"SRV001"}] [{"id":"SRV002"}]`, "", "unexpected data after the entry array at offset 17"},
+		{"json with garbage", FormatJSON, `[{"id":"SRV001"}]]`, "", "unexpected data"},
+		{"json with text", FormatJSON, "[]\nnot json", "", "unexpected data"},
+		{"jsonl", FormatJSONLines, "{\"id\":\"SRV002\"}\n\n  \n{\"id\":\"SRV001\",\"replies\":[\"a\"]}\n", "SRV002,SRV001", ""},
+		{"jsonl error line", FormatJSONLines, "{\"id\":\"SRV002\"}\n\n{\"id\":\n{\"id\":\"SRV001\"}\n", "", "line 3: "},
+		{"unknown format", "toml", "id = 1", "", `catalog: unknown format "toml"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			entries, err := ReadEntries(strings.NewReader(tc.input), tc.format)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("error %v, want %q", err, tc.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			if got := strings.Join(ids, ","); got != tc.ids {
+				t.Errorf("read %s, want %s", got, tc.ids)
+			}
+		})
+	}
+}
+
+func TestLoadReader(t *testing.T) {
+	cat, err := LoadReader(strings.NewReader(`[{"id":"SRV002","text":"a"},{"id":"SRV002","text":"b"},{"id":"SRV001"}]`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cat) != 2 || cat["SRV002"].Text != "b" {
+		t.Errorf("catalog %+v, want later duplicates to win", cat)
+	}
+	if _, err := LoadReader(strings.NewReader("{"), FormatJSONLines); err == nil {
+		t.Error("LoadReader returned no decode error")
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	for path, want := range map[string]string{
+		"catalog.yaml":       FormatYAML,
+		"dir.v2/catalog.YML": FormatYAML,
+		"catalog.json":       FormatJSON,
+		"catalog.jsonl":      FormatJSONLines,
+		"catalog.NDJSON":     FormatJSONLines,
+		"catalog":            FormatYAML,
+		"catalog.txt":        FormatYAML,
+	} {
+		if got := FormatOf(path); got != want {
+			t.Errorf("FormatOf(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	// Lines of "ID severity text"
+	lines := func(r io.Reader) ([]CatalogEntry, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		var entries []CatalogEntry
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			f := strings.SplitN(line, " ", 3)
+			if len(f) != 3 {
+				return nil, errors.New("want ID SEVERITY TEXT")
+			}
+			entries = append(entries, CatalogEntry{ID: f[0], Severity: f[1], Text: f[2]})
+		}
+		return entries, nil
+	}
+	RegisterDecoder("test-lines", lines, ".Lines", ".msgs")
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		delete(decoders, "test-lines")
+		delete(extensions, ".lines")
+		delete(extensions, ".msgs")
+		formatsMu.Unlock()
+	})
+
+	if got := FormatOf("app.lines"); got != "test-lines" {
+		t.Errorf("FormatOf(app.lines) = %q", got)
+	}
+	if got := FormatOf("app.MSGS"); got != "test-lines" {
+		t.Errorf("FormatOf(app.MSGS) = %q", got)
+	}
+	cat, err := LoadReader(strings.NewReader("SRV002 ERROR Failed to bind\nSRV001 INFO Starting\n"), FormatOf("x.lines"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cat) != 2 || cat["SRV002"].Text != "Failed to bind" || cat["SRV001"].Severity != "INFO" {
+		t.Errorf("catalog %+v", cat)
+	}
+	if _, err := ReadEntries(strings.NewReader("bad"), "test-lines"); err == nil {
+		t.Error("decoder error was lost")
+	}
+
+	// Registering again replaces the decoder
+	RegisterDecoder("test-lines", func(io.Reader) ([]CatalogEntry, error) { return nil, nil })
+	if entries, err := ReadEntries(strings.NewReader("SRV001 INFO x"), "test-lines"); err != nil || len(entries) != 0 {
+		t.Errorf("replaced decoder read %v, %v", entries, err)
+	}
+}
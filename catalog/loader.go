@@ -2,36 +2,66 @@ package catalog
 
 import (
 	"os"
+	"strings"
 	"time"
 
 	"github.com/martencassel/opsmsg/message"
-	"gopkg.in/yaml.v3"
 )
 
 type CatalogEntry struct {
-	ID       string   `yaml:"id"`
-	Severity string   `yaml:"severity"`
-	Text     string   `yaml:"text"`
-	Help     string   `yaml:"help"`
-	Replies  []string `yaml:"replies"`
+	ID       string   `yaml:"id" json:"id"`
+	Severity string   `yaml:"severity" json:"severity"`
+	Text     string   `yaml:"text" json:"text"`
+	Help     string   `yaml:"help" json:"help,omitempty"`
+	Replies  []string `yaml:"replies" json:"replies,omitempty"`
+	Links    []string `yaml:"links" json:"links,omitempty"`
+	// History records how the entry changed between releases
+	History []Change `yaml:"history" json:"history,omitempty"`
+}
+
+// Change is one entry of a message's history
+type Change struct {
+	Version string `yaml:"version" json:"version"`
+	Note    string `yaml:"note" json:"note"`
 }
 
 type Catalog map[string]CatalogEntry
 
+// Load reads a catalog file; see LoadFile
 func Load(path string) (Catalog, error) {
-	data, err := os.ReadFile(path)
+	return LoadFile(path)
+}
+
+// LoadFile reads a catalog file in the format given by its extension:
+// YAML (.yaml, .yml), JSON (.json) or JSON Lines (.jsonl, .ndjson).
+// Other extensions are read as YAML.
+func LoadFile(path string) (Catalog, error) {
+	entries, err := LoadEntries(path)
 	if err != nil {
 		return nil, err
 	}
-	var entries []CatalogEntry
-	if err := yaml.Unmarshal(data, &entries); err != nil {
+	return FromEntries(entries), nil
+}
+
+// LoadEntries reads the entries of a catalog file in file order, keeping
+// duplicates, for tools that need to check the file itself
+func LoadEntries(path string) ([]CatalogEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
 		return nil, err
 	}
+	defer f.Close()
+	return ReadEntries(f, FormatOf(path))
+}
+
+// FromEntries builds a catalog from entries; later entries replace earlier
+// ones with the same ID
+func FromEntries(entries []CatalogEntry) Catalog {
 	catalog := make(Catalog)
 	for _, e := range entries {
 		catalog[e.ID] = e
 	}
-	return catalog, nil
+	return catalog
 }
 
 func (c Catalog) New(id string, ctx map[string]string) message.Message {
@@ -44,7 +74,29 @@ func (c Catalog) New(id string, ctx map[string]string) message.Message {
 		Timestamp: time.Now(),
 		Help:      e.Help,
 		Replies:   e.Replies,
+		Links:     e.Links,
+	}
+}
+
+// Lookup finds the entry for ref, which may be a plain ID such as SRV002
+// or a compact ID such as SRV002E written in one of styles (default:
+// message.MainframeIDs). A compact ID whose severity suffix does not match
+// the entry, such as SRV002I for an ERROR message, is not found.
+func (c Catalog) Lookup(ref string, styles ...message.IDStyle) (CatalogEntry, bool) {
+	if e, ok := c[ref]; ok {
+		return e, true
+	}
+	if len(styles) == 0 {
+		styles = []message.IDStyle{message.MainframeIDs}
+	}
+	for _, style := range styles {
+		if id, sev, ok := style.Parse(ref); ok {
+			if e, ok := c[id]; ok && (sev == "" || message.Severity(e.Severity) == sev) {
+				return e, true
+			}
+		}
 	}
+	return CatalogEntry{}, false
 }
 
 func Merge(catalogs ...Catalog) Catalog {
@@ -56,3 +108,25 @@ func Merge(catalogs ...Catalog) Catalog {
 	}
 	return merged
 }
+
+// Cause returns the part of the help text after "Cause:", or the whole
+// help text when it does not follow the Cause/Recovery convention
+func (e CatalogEntry) Cause() string {
+	cause, _ := splitHelp(e.Help)
+	return cause
+}
+
+// Recovery returns the part of the help text after "Recovery:"
+func (e CatalogEntry) Recovery() string {
+	_, recovery := splitHelp(e.Help)
+	return recovery
+}
+
+func splitHelp(help string) (cause, recovery string) {
+	cause = help
+	if i := strings.Index(help, "Recovery:"); i >= 0 {
+		cause, recovery = help[:i], strings.TrimSpace(help[i+len("Recovery:"):])
+	}
+	cause = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(cause), "Cause:"))
+	return cause, recovery
+}
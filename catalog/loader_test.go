@@ -0,0 +1,36 @@
+package catalog
+
+import (
+	"testing"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestLookup(t *testing.T) {
+	c := FromEntries([]CatalogEntry{
+		{ID: "SRV002", Severity: "ERROR", Text: "Failed to bind to port {port}"},
+		{ID: "SRV001", Severity: "INFO", Text: "Server starting"},
+	})
+	ops := message.IDStyle{Prefix: "OPS", Suffixes: message.MainframeIDs.Suffixes}
+
+	for _, tc := range []struct {
+		ref    string
+		styles []message.IDStyle
+		want   string
+	}{
+		{"SRV002", nil, "SRV002"},
+		{"SRV002E", nil, "SRV002"},
+		{"SRV001I", nil, "SRV001"},
+		{"SRV002I", nil, ""}, // suffix says INFO, the entry is ERROR
+		{"SRV002S", nil, ""},
+		{"SRV003E", nil, ""},
+		{"OPSSRV002E", []message.IDStyle{ops}, "SRV002"},
+		{"OPSSRV002", []message.IDStyle{ops}, "SRV002"},
+		{"OPSSRV002W", []message.IDStyle{ops}, ""},
+	} {
+		e, ok := c.Lookup(tc.ref, tc.styles...)
+		if ok != (tc.want != "") || e.ID != tc.want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", tc.ref, e.ID, ok, tc.want)
+		}
+	}
+}
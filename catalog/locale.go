@@ -0,0 +1,21 @@
+package catalog
+
+// Localize returns base with the text and help of the entries in
+// translation. A locale catalog only needs IDs, text and help; entries it
+// leaves out or leaves empty keep the base wording, and severities,
+// replies and links always come from base.
+func Localize(base, translation Catalog) Catalog {
+	localized := make(Catalog, len(base))
+	for id, e := range base {
+		if t, ok := translation[id]; ok {
+			if t.Text != "" {
+				e.Text = t.Text
+			}
+			if t.Help != "" {
+				e.Help = t.Help
+			}
+		}
+		localized[id] = e
+	}
+	return localized
+}
@@ -0,0 +1,117 @@
+package catalog
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Problem is an issue Validate found in a catalog
+type Problem struct {
+	// Index is the position of the entry in the file, counting from 0
+	Index int
+	// ID is the ID of the entry, possibly empty
+	ID      string
+	Message string
+	// Warning marks problems that do not stop the catalog from working
+	Warning bool
+}
+
+func (p Problem) String() string {
+	kind := "error"
+	if p.Warning {
+		kind = "warning"
+	}
+	id := p.ID
+	if id == "" {
+		id = fmt.Sprintf("entry %d", p.Index+1)
+	}
+	return fmt.Sprintf("%s: %s: %s", id, kind, p.Message)
+}
+
+// idPattern is the conventional ID shape: a component code followed by a
+// number, e.g. SRV001
+var idPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]*[0-9]{3}$`)
+
+// Validate checks catalog entries in file order. Errors are missing or
+// duplicate IDs, unknown severities, empty or malformed text, empty
+// replies and links that are not absolute URLs. IDs that do not follow
+// the usual shape and missing help text are warnings.
+func Validate(entries []CatalogEntry) []Problem {
+	var problems []Problem
+	seen := make(map[string]int)
+
+	for i, e := range entries {
+		add := func(warning bool, format string, args ...interface{}) {
+			problems = append(problems, Problem{Index: i, ID: e.ID, Message: fmt.Sprintf(format, args...), Warning: warning})
+		}
+
+		switch {
+		case e.ID == "":
+			add(false, "missing id")
+		case !idPattern.MatchString(e.ID):
+			add(true, "id does not look like ABC123")
+		}
+		if first, ok := seen[e.ID]; ok && e.ID != "" {
+			add(false, "duplicate id, first defined as entry %d", first+1)
+		} else {
+			seen[e.ID] = i
+		}
+
+		if message.Severity(e.Severity).Rank() == 0 {
+			add(false, "unknown severity %q (want INFO, WARN, ERROR or CRITICAL)", e.Severity)
+		}
+
+		if strings.TrimSpace(e.Text) == "" {
+			add(false, "missing text")
+		} else if err := checkPlaceholders(e.Text); err != nil {
+			add(false, "text: %v", err)
+		}
+
+		if strings.TrimSpace(e.Help) == "" {
+			add(true, "missing help")
+		}
+
+		for n, r := range e.Replies {
+			if strings.TrimSpace(r) == "" {
+				add(false, "reply %d is empty", n+1)
+			}
+		}
+		for _, link := range e.Links {
+			if u, err := url.Parse(link); err != nil || !u.IsAbs() {
+				add(false, "link %q is not an absolute URL", link)
+			}
+		}
+	}
+	return problems
+}
+
+// checkPlaceholders reports unbalanced braces and empty or nested
+// placeholders
+func checkPlaceholders(text string) error {
+	open := -1
+	for i, r := range text {
+		switch r {
+		case '{':
+			if open >= 0 {
+				return fmt.Errorf("nested '{' at offset %d", i)
+			}
+			open = i
+		case '}':
+			if open < 0 {
+				return fmt.Errorf("unmatched '}' at offset %d", i)
+			}
+			if strings.TrimSpace(text[open+1:i]) == "" {
+				return fmt.Errorf("empty placeholder at offset %d", open)
+			}
+			open = -1
+		}
+	}
+	if open >= 0 {
+		return fmt.Errorf("unclosed '{' at offset %d", open)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+)
+
+// catalogFlags selects the catalogs a command works on
+type catalogFlags struct {
+	paths   []string
+	builtin bool
+}
+
+func (c *catalogFlags) register(fs *flag.FlagSet) {
+	fs.Var((*stringList)(&c.paths), "c", "catalog `file`; may be repeated, later files override earlier ones")
+	fs.BoolVar(&c.builtin, "builtin", true, "load the built-in catalog before the others")
+}
+
+// files returns the catalog files in merge order
+func (c *catalogFlags) files() []string {
+	if len(c.paths) > 0 {
+		return c.paths
+	}
+	if env := os.Getenv("OPSMSG_CATALOG"); env != "" {
+		return filepath.SplitList(env)
+	}
+	return nil
+}
+
+// load merges the selected catalogs
+func (c *catalogFlags) load() (catalog.Catalog, error) {
+	var catalogs []catalog.Catalog
+	if c.builtin {
+		catalogs = append(catalogs, catalog.Builtin())
+	}
+	for _, path := range c.files() {
+		cat, err := catalog.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, cat)
+	}
+	return catalog.Merge(catalogs...), nil
+}
+
+// stringList is a flag.Value collecting repeated flags
+type stringList []string
+
+func (p *stringList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *stringList) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+// newFlagSet returns a FlagSet that reports errors to stderr instead of
+// exiting
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s\n\nFlags:\n", strings.TrimSpace("opsmsg "+name+" [flags] "+args))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseError returns the exit status for a flag parsing error; the
+// FlagSet has already printed it
+func parseError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
+
+// sortedEntries returns the entries of c ordered by ID
+func sortedEntries(c catalog.Catalog) []catalog.CatalogEntry {
+	entries := make([]catalog.CatalogEntry, 0, len(c))
+	for _, e := range c {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries
+}
+
+// parseSeverities parses a comma-separated severity list
+func parseSeverities(s string) (map[message.Severity]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	set := make(map[message.Severity]bool)
+	for _, name := range strings.Split(s, ",") {
+		sev := message.Severity(strings.ToUpper(strings.TrimSpace(name)))
+		if sev.Rank() == 0 {
+			return nil, fmt.Errorf("unknown severity %q", name)
+		}
+		set[sev] = true
+	}
+	return set, nil
+}
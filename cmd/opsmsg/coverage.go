@@ -0,0 +1,435 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+// idShape matches string literals that look like message IDs, so calls
+// such as errors.New("...") are not taken for catalog lookups
+var idShape = regexp.MustCompile(`^[A-Z][A-Z0-9]*[0-9]{3}$`)
+
+// majorVersion matches the last element of import paths such as
+// math/rand/v2
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// runCoverage scans Go source for the message IDs it uses and compares
+// them with the catalogs. It exits with 1 when code uses IDs the catalogs
+// do not have (with -strict, also when entries are unused).
+func runCoverage(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("coverage", "[packages]", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	var funcs stringList
+	fs.Var(&funcs, "func", "also treat calls to `name` as taking a message ID first; may be repeated")
+	tests := fs.Bool("tests", false, "include _test.go files")
+	format := fs.String("format", "text", "output `format`: text or json")
+	strict := fs.Bool("strict", false, "also exit with 1 when catalog entries are unused")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg coverage: %v\n", err)
+		return 1
+	}
+	files, err := goFiles(patterns, *tests)
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg coverage: %v\n", err)
+		return 1
+	}
+	s := scanner{
+		catalog: cat,
+		funcs:   make(map[string]bool),
+		methods: map[string]bool{"New": true},
+		fset:    token.NewFileSet(),
+	}
+	for _, name := range funcs {
+		s.funcs[name] = true
+	}
+	if err := s.scan(files); err != nil {
+		fmt.Fprintf(stderr, "opsmsg coverage: %v\n", err)
+		return 1
+	}
+
+	// Entries of the built-in catalog only count as unused when no other
+	// catalog is loaded, since most programs use a few of them
+	owned := cat
+	if paths := cf.files(); len(paths) > 0 {
+		owned = make(catalog.Catalog)
+		for _, path := range paths {
+			c, err := catalog.Load(path)
+			if err != nil {
+				fmt.Fprintf(stderr, "opsmsg coverage: %v\n", err)
+				return 1
+			}
+			owned = catalog.Merge(owned, c)
+		}
+	}
+	r := s.report(owned)
+
+	switch *format {
+	case "text":
+		writeCoverageText(stdout, r)
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(r)
+	default:
+		fmt.Fprintf(stderr, "opsmsg coverage: unknown format %q\n", *format)
+		return 2
+	}
+	if len(r.Missing) > 0 || (*strict && len(r.Unused) > 0) {
+		return 1
+	}
+	return 0
+}
+
+// goFiles expands package patterns like go build does: "dir" is the Go
+// files in dir and "dir/..." also those in its subdirectories, leaving
+// out vendor, testdata and directories starting with "." or "_". A .go
+// file is taken as is.
+func goFiles(patterns []string, tests bool) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	wanted := func(name string) bool {
+		return strings.HasSuffix(name, ".go") && (tests || !strings.HasSuffix(name, "_test.go"))
+	}
+
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, ".go") {
+			add(filepath.Clean(pattern))
+			continue
+		}
+		root, recursive := strings.CutSuffix(pattern, "...")
+		root = filepath.Clean(strings.TrimSuffix(root, "/"))
+		if root == "" {
+			root = "."
+		}
+		if !recursive {
+			dirents, err := os.ReadDir(root)
+			if err != nil {
+				return nil, err
+			}
+			for _, d := range dirents {
+				if !d.IsDir() && wanted(d.Name()) {
+					add(filepath.Join(root, d.Name()))
+				}
+			}
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			name := d.Name()
+			if d.IsDir() {
+				if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if wanted(name) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// scanner finds the message IDs Go source refers to
+type scanner struct {
+	catalog catalog.Catalog
+	// funcs are the names of functions and methods whose first argument
+	// is a message ID
+	funcs map[string]bool
+	// methods are like funcs but only match method calls: x.New(...)
+	// where x is not an imported package, as in errors.New
+	methods map[string]bool
+	fset    *token.FileSet
+	refs    []idRef
+}
+
+// idRef is one use of a message ID in source
+type idRef struct {
+	ID       string `json:"id"`
+	Package  string `json:"package"`
+	Position string `json:"position"`
+}
+
+func (s *scanner) scan(files []string) error {
+	// Parse everything first so constants declared in one file of a
+	// package resolve in the others
+	parsed := make(map[string][]*ast.File)
+	var dirs []string
+	for _, path := range files {
+		f, err := parser.ParseFile(s.fset, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(path)
+		if parsed[dir] == nil {
+			dirs = append(dirs, dir)
+		}
+		parsed[dir] = append(parsed[dir], f)
+	}
+
+	for _, dir := range dirs {
+		consts := stringConsts(parsed[dir])
+		for _, f := range parsed[dir] {
+			pkg := importPath(dir)
+			imports := importNames(f)
+			ast.Inspect(f, func(n ast.Node) bool {
+				if call, ok := n.(*ast.CallExpr); ok {
+					s.call(pkg, call, consts, imports)
+				}
+				return true
+			})
+		}
+	}
+	return nil
+}
+
+// importPath returns the import path of the package in dir, found from
+// the nearest go.mod, or dir itself outside a module
+func importPath(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return filepath.ToSlash(dir)
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if data, err := os.ReadFile(filepath.Join(root, "go.mod")); err == nil {
+			if module := modfileModule(data); module != "" {
+				rel, _ := filepath.Rel(root, abs)
+				return path.Join(module, filepath.ToSlash(rel))
+			}
+		}
+		if root == filepath.Dir(root) {
+			return filepath.ToSlash(dir)
+		}
+	}
+}
+
+// modfileModule returns the module path declared in a go.mod file
+func modfileModule(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
+
+// importNames returns the names f refers to its imports by
+func importNames(f *ast.File) map[string]bool {
+	names := make(map[string]bool)
+	for _, spec := range f.Imports {
+		if spec.Name != nil {
+			names[spec.Name.Name] = true
+			continue
+		}
+		// The package name is usually the last path element, without a
+		// major version: math/rand/v2 is rand, gopkg.in/yaml.v3 is yaml
+		p, _ := strconv.Unquote(spec.Path.Value)
+		elems := strings.Split(p, "/")
+		name := elems[len(elems)-1]
+		if len(elems) > 1 && majorVersion.MatchString(name) {
+			name = elems[len(elems)-2]
+		}
+		name, _, _ = strings.Cut(name, ".")
+		names[name] = true
+	}
+	return names
+}
+
+// call records the message ID call refers to, if any. Receivers are not
+// type-checked: the method names only rule out calls on imported
+// packages, so a New method of another type taking an ID-shaped string
+// is still counted.
+func (s *scanner) call(pkg string, call *ast.CallExpr, consts map[string]string, imports map[string]bool) {
+	var name string
+	method := false
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		name = fn.Name
+	case *ast.SelectorExpr:
+		name = fn.Sel.Name
+		x, ok := fn.X.(*ast.Ident)
+		method = !ok || !imports[x.Name]
+	default:
+		return
+	}
+
+	// Generated constructors are named after a catalog message: SRV001()
+	// or NewSRV001()
+	if id := strings.TrimPrefix(name, "New"); id != "" {
+		if _, ok := s.catalog[id]; ok {
+			s.add(pkg, id, call.Pos())
+			return
+		}
+	}
+	if !(s.funcs[name] || method && s.methods[name]) || len(call.Args) == 0 {
+		return
+	}
+	var id string
+	switch arg := call.Args[0].(type) {
+	case *ast.BasicLit:
+		if arg.Kind == token.STRING {
+			id, _ = strconv.Unquote(arg.Value)
+		}
+	case *ast.Ident:
+		id = consts[arg.Name]
+	}
+	if _, ok := s.catalog[id]; ok || idShape.MatchString(id) {
+		s.add(pkg, id, call.Pos())
+	}
+}
+
+func (s *scanner) add(pkg, id string, pos token.Pos) {
+	p := s.fset.Position(pos)
+	s.refs = append(s.refs, idRef{ID: id, Package: pkg, Position: fmt.Sprintf("%s:%d", p.Filename, p.Line)})
+}
+
+// stringConsts collects the string constants declared in files, at
+// package level or in functions
+func stringConsts(files []*ast.File) map[string]string {
+	consts := make(map[string]string)
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			decl, ok := n.(*ast.GenDecl)
+			if !ok || decl.Tok != token.CONST {
+				return true
+			}
+			for _, spec := range decl.Specs {
+				vs := spec.(*ast.ValueSpec)
+				for i, name := range vs.Names {
+					if i >= len(vs.Values) {
+						break
+					}
+					if lit, ok := vs.Values[i].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						if v, err := strconv.Unquote(lit.Value); err == nil {
+							consts[name.Name] = v
+						}
+					}
+				}
+			}
+			return false
+		})
+	}
+	return consts
+}
+
+// coverageReport compares the IDs used in source with the catalogs
+type coverageReport struct {
+	Packages []packageUsage `json:"packages"`
+	// Entries and Used count the entries of the catalogs checked for
+	// unused entries, and how many of them the source uses
+	Entries int `json:"entries"`
+	Used    int `json:"used"`
+	// Unused lists catalog IDs no source refers to
+	Unused []string `json:"unused"`
+	// Missing lists uses of IDs that are in no catalog
+	Missing []idRef `json:"missing"`
+}
+
+// packageUsage counts the message IDs one package uses
+type packageUsage struct {
+	Package string `json:"package"`
+	// References counts calls and IDs the distinct message IDs
+	References int `json:"references"`
+	IDs        int `json:"ids"`
+}
+
+// report builds the report; owned are the entries checked for use
+func (s *scanner) report(owned catalog.Catalog) coverageReport {
+	r := coverageReport{Entries: len(owned), Unused: []string{}, Missing: []idRef{}}
+	used := make(map[string]bool)
+	packages := make(map[string]*packageUsage)
+	ids := make(map[string]map[string]bool)
+	usage := func(pkg string) *packageUsage {
+		if packages[pkg] == nil {
+			packages[pkg] = &packageUsage{Package: pkg}
+			ids[pkg] = make(map[string]bool)
+		}
+		return packages[pkg]
+	}
+
+	for _, ref := range s.refs {
+		used[ref.ID] = true
+		u := usage(ref.Package)
+		u.References++
+		if !ids[ref.Package][ref.ID] {
+			ids[ref.Package][ref.ID] = true
+			u.IDs++
+		}
+		if _, ok := s.catalog[ref.ID]; !ok {
+			r.Missing = append(r.Missing, ref)
+		}
+	}
+
+	for _, e := range sortedEntries(owned) {
+		if used[e.ID] {
+			r.Used++
+		} else {
+			r.Unused = append(r.Unused, e.ID)
+		}
+	}
+	for _, u := range packages {
+		r.Packages = append(r.Packages, *u)
+	}
+	sort.Slice(r.Packages, func(i, j int) bool { return r.Packages[i].Package < r.Packages[j].Package })
+	if r.Packages == nil {
+		r.Packages = []packageUsage{}
+	}
+	return r
+}
+
+func writeCoverageText(w io.Writer, r coverageReport) {
+	if len(r.Packages) > 0 {
+		fmt.Fprintf(w, "%-40s %10s %5s\n", "PACKAGE", "REFERENCES", "IDS")
+		for _, u := range r.Packages {
+			fmt.Fprintf(w, "%-40s %10d %5d\n", u.Package, u.References, u.IDs)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, ref := range r.Missing {
+		fmt.Fprintf(w, "%s: missing: %s is not in the catalog\n", ref.Position, ref.ID)
+	}
+	for _, id := range r.Unused {
+		fmt.Fprintf(w, "unused: %s\n", id)
+	}
+	percent := 100.0
+	if r.Entries > 0 {
+		percent = 100 * float64(r.Used) / float64(r.Entries)
+	}
+	fmt.Fprintf(w, "%d of %d catalog entries used (%.1f%%), %d missing\n", r.Used, r.Entries, percent, len(r.Missing))
+}
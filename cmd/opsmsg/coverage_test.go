@@ -0,0 +1,175 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+// writeTree creates files under a temporary directory and returns it
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGoFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"main.go":              "package main",
+		"main_test.go":         "package main",
+		"README.md":            "",
+		"api/api.go":           "package api",
+		"api/vendor/v/v.go":    "package v",
+		"api/testdata/td.go":   "package td",
+		"api/_old/old.go":      "package old",
+		"api/.cache/cached.go": "package cached",
+		"api/deep/deep.go":     "package deep",
+	})
+	rel := func(files []string) []string {
+		var names []string
+		for _, f := range files {
+			r, _ := filepath.Rel(root, f)
+			names = append(names, filepath.ToSlash(r))
+		}
+		return names
+	}
+
+	for _, tc := range []struct {
+		patterns []string
+		tests    bool
+		want     []string
+	}{
+		{[]string{root}, false, []string{"main.go"}},
+		{[]string{root}, true, []string{"main.go", "main_test.go"}},
+		{[]string{root + "/..."}, false, []string{"api/api.go", "api/deep/deep.go", "main.go"}},
+		{[]string{root + "/api/...", root + "/api", filepath.Join(root, "main.go")}, false, []string{"api/api.go", "api/deep/deep.go", "main.go"}},
+	} {
+		files, err := goFiles(tc.patterns, tc.tests)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := rel(files); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("goFiles(%v, %v) = %v, want %v", tc.patterns, tc.tests, got, tc.want)
+		}
+	}
+
+	if _, err := goFiles([]string{filepath.Join(root, "missing")}, false); err == nil {
+		t.Error("missing directory: no error")
+	}
+}
+
+func TestStringConsts(t *testing.T) {
+	src := `package p
+
+const Started = "SRV001"
+
+const (
+	Failed, Retried = "SRV002", "SRV003"
+	port            = 8080
+	raw             = ` + "`SRV004`" + `
+	typed string    = "SRV005"
+	noValue
+)
+
+func f() {
+	const local = "DB001"
+}
+`
+	f, err := parser.ParseFile(token.NewFileSet(), "p.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := stringConsts([]*ast.File{f})
+	want := map[string]string{
+		"Started": "SRV001", "Failed": "SRV002", "Retried": "SRV003",
+		"raw": "SRV004", "typed": "SRV005", "local": "DB001",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("stringConsts = %v, want %v", got, want)
+	}
+}
+
+const coverageSource = `package app
+
+import (
+	"crypto/elliptic"
+	"errors"
+	msgs "example.com/app/messages"
+	"math/rand/v2"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+const bindFailed = "SRV002"
+
+func run(c catalog.Catalog) {
+	c.New("SRV001", nil)
+	c.New(bindFailed, nil)
+	catalog.Builtin().New("APP404", nil)
+	NewSRV003()
+	msgs.SRV003()
+
+	// Not message IDs
+	elliptic.P256()
+	errors.New("ABC123")
+	msgs.New("APP500")
+	rand.New(nil)
+	c.New("not an id", nil)
+}
+`
+
+func TestCoverageReport(t *testing.T) {
+	root := writeTree(t, map[string]string{"app/app.go": coverageSource})
+	cat := catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV001", Severity: "INFO", Text: "Started"},
+		{ID: "SRV002", Severity: "ERROR", Text: "Bind failed"},
+		{ID: "SRV003", Severity: "CRITICAL", Text: "Stopped"},
+		{ID: "SRV004", Severity: "INFO", Text: "Unused"},
+	})
+	s := scanner{catalog: cat, funcs: map[string]bool{}, methods: map[string]bool{"New": true}, fset: token.NewFileSet()}
+	files, err := goFiles([]string{root + "/..."}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.scan(files); err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, ref := range s.refs {
+		ids = append(ids, ref.ID)
+	}
+	if got := strings.Join(ids, " "); got != "SRV001 SRV002 APP404 SRV003 SRV003" {
+		t.Errorf("found %s", got)
+	}
+
+	r := s.report(cat)
+	if r.Entries != 4 || r.Used != 3 {
+		t.Errorf("entries, used = %d, %d, want 4, 3", r.Entries, r.Used)
+	}
+	if !reflect.DeepEqual(r.Unused, []string{"SRV004"}) {
+		t.Errorf("unused = %v", r.Unused)
+	}
+	if len(r.Missing) != 1 || r.Missing[0].ID != "APP404" || !strings.HasSuffix(r.Missing[0].Position, "app.go:17") {
+		t.Errorf("missing = %+v", r.Missing)
+	}
+	if len(r.Packages) != 1 || r.Packages[0].References != 5 || r.Packages[0].IDs != 4 {
+		t.Errorf("packages = %+v", r.Packages)
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/render"
+)
+
+// runDiff compares two catalog files. It exits with 1 when there are
+// breaking changes (or any, with -fail-on any) so it can gate releases.
+func runDiff(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("diff", "old.yaml new.yaml", stderr)
+	format := fs.String("format", "text", "output `format`: text, json or markdown")
+	failOn := fs.String("fail-on", "breaking", "exit with 1 on `changes`: breaking, any or none")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return 2
+	}
+	switch *failOn {
+	case "breaking", "any", "none":
+	default:
+		fmt.Fprintf(stderr, "opsmsg diff: unknown -fail-on value %q\n", *failOn)
+		return 2
+	}
+
+	from, err := catalog.Load(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg diff: %v\n", err)
+		return 1
+	}
+	to, err := catalog.Load(fs.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg diff: %v\n", err)
+		return 1
+	}
+	diffs := catalog.Diff(from, to)
+
+	switch *format {
+	case "text":
+		writeDiffText(stdout, diffs)
+	case "json":
+		if diffs == nil {
+			diffs = []catalog.Difference{}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(diffs)
+	case "markdown":
+		writeDiffMarkdown(stdout, diffs)
+	default:
+		fmt.Fprintf(stderr, "opsmsg diff: unknown format %q\n", *format)
+		return 2
+	}
+
+	breaking := 0
+	for _, d := range diffs {
+		if d.Breaking {
+			breaking++
+		}
+	}
+	switch *failOn {
+	case "breaking":
+		if breaking > 0 {
+			return 1
+		}
+	case "any":
+		if len(diffs) > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// describe returns a one-line summary of d
+func describe(d catalog.Difference) string {
+	switch d.Kind {
+	case catalog.DiffAdded:
+		return fmt.Sprintf("added: %q", d.New)
+	case catalog.DiffRemoved:
+		return fmt.Sprintf("removed: %q", d.Old)
+	case catalog.DiffSeverity:
+		return fmt.Sprintf("severity changed from %s to %s", d.Old, d.New)
+	case catalog.DiffPlaceholderRenamed:
+		return fmt.Sprintf("placeholder {%s} renamed to {%s}", d.Old, d.New)
+	case catalog.DiffPlaceholderRemoved:
+		return fmt.Sprintf("placeholder {%s} removed", d.Old)
+	case catalog.DiffPlaceholderAdded:
+		return fmt.Sprintf("placeholder {%s} added", d.New)
+	default:
+		return fmt.Sprintf("%s changed from %q to %q", d.Kind, d.Old, d.New)
+	}
+}
+
+func writeDiffText(w io.Writer, diffs []catalog.Difference) {
+	if len(diffs) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	for _, breaking := range []bool{true, false} {
+		title := "Non-breaking changes:"
+		if breaking {
+			title = "Breaking changes:"
+		}
+		printed := false
+		for _, d := range diffs {
+			if d.Breaking != breaking {
+				continue
+			}
+			if !printed {
+				fmt.Fprintln(w, title)
+				printed = true
+			}
+			fmt.Fprintf(w, "  %s: %s\n", d.ID, describe(d))
+		}
+	}
+}
+
+func writeDiffMarkdown(w io.Writer, diffs []catalog.Difference) {
+	var b strings.Builder
+	for _, breaking := range []bool{true, false} {
+		title := "### Non-breaking changes\n\n"
+		if breaking {
+			title = "### Breaking changes\n\n"
+		}
+		printed := false
+		for _, d := range diffs {
+			if d.Breaking != breaking {
+				continue
+			}
+			if !printed {
+				b.WriteString(title)
+				printed = true
+			}
+			b.WriteString("- `" + d.ID + "` " + render.EscapeMarkdown(describe(d)) + "\n")
+		}
+		if printed {
+			b.WriteString("\n")
+		}
+	}
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/render"
+)
+
+// runDocs generates operator documentation for the selected catalogs: a
+// static HTML site, or one Markdown document or man page
+func runDocs(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("docs", "", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	format := fs.String("format", "html", "output `format`: html, markdown or man")
+	out := fs.String("o", "", "output directory for html (default \"docs\"), file for markdown and man (default: stdout)")
+	title := fs.String("title", "Messages and Codes", "document `title`")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg docs: %v\n", err)
+		return 1
+	}
+	d := newDocument(*title, sortedEntries(cat))
+
+	switch *format {
+	case "html":
+		dir := *out
+		if dir == "" {
+			dir = "docs"
+		}
+		err = d.writeSite(dir)
+	case "markdown", "man":
+		w := stdout
+		if *out != "" {
+			f, err := os.Create(*out)
+			if err != nil {
+				fmt.Fprintf(stderr, "opsmsg docs: %v\n", err)
+				return 1
+			}
+			defer f.Close()
+			w = f
+		}
+		if *format == "markdown" {
+			err = d.writeMarkdown(w)
+		} else {
+			err = d.writeMan(w)
+		}
+	default:
+		fmt.Fprintf(stderr, "opsmsg docs: unknown format %q\n", *format)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg docs: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// document is a catalog arranged for reading
+type document struct {
+	Title      string
+	Generated  string
+	Entries    []docEntry
+	Components []docGroup
+	Severities []docGroup
+}
+
+type docEntry struct {
+	catalog.CatalogEntry
+	Component    string
+	Placeholders []string
+}
+
+type docGroup struct {
+	Name    string
+	Entries []docEntry
+}
+
+// componentPattern extracts the component code from an ID: SRV from SRV002
+var componentPattern = regexp.MustCompile(`^[A-Za-z]+`)
+
+func newDocument(title string, entries []catalog.CatalogEntry) document {
+	d := document{Title: title, Generated: time.Now().Format("2006-01-02")}
+
+	components := make(map[string][]docEntry)
+	severities := make(map[string][]docEntry)
+	for _, e := range entries {
+		de := docEntry{
+			CatalogEntry: e,
+			Component:    componentPattern.FindString(e.ID),
+			Placeholders: message.Placeholders(e.Text),
+		}
+		if de.Component == "" {
+			de.Component = "Other"
+		}
+		d.Entries = append(d.Entries, de)
+		components[de.Component] = append(components[de.Component], de)
+		severities[e.Severity] = append(severities[e.Severity], de)
+	}
+
+	for name, list := range components {
+		d.Components = append(d.Components, docGroup{Name: name, Entries: list})
+	}
+	sort.Slice(d.Components, func(i, j int) bool { return d.Components[i].Name < d.Components[j].Name })
+
+	for name, list := range severities {
+		d.Severities = append(d.Severities, docGroup{Name: name, Entries: list})
+	}
+	// Most severe first
+	sort.Slice(d.Severities, func(i, j int) bool {
+		ri := message.Severity(d.Severities[i].Name).Rank()
+		rj := message.Severity(d.Severities[j].Name).Rank()
+		if ri != rj {
+			return ri > rj
+		}
+		return d.Severities[i].Name < d.Severities[j].Name
+	})
+	return d
+}
+
+// textSegment is a run of message text, possibly a placeholder
+type textSegment struct {
+	Text        string
+	Placeholder bool
+}
+
+// segments splits text into literal runs and {placeholders}
+func segments(text string) []textSegment {
+	var out []textSegment
+	for text != "" {
+		open := strings.Index(text, "{")
+		if open < 0 {
+			break
+		}
+		end := strings.Index(text[open:], "}")
+		if end < 0 {
+			break
+		}
+		end += open
+		if open > 0 {
+			out = append(out, textSegment{Text: text[:open]})
+		}
+		out = append(out, textSegment{Text: text[open+1 : end], Placeholder: true})
+		text = text[end+1:]
+	}
+	if text != "" {
+		out = append(out, textSegment{Text: text})
+	}
+	return out
+}
+
+var docFuncs = template.FuncMap{
+	"segments": segments,
+	"lower":    strings.ToLower,
+	"page":     func(id string) string { return "messages/" + pageName(id) + ".html" },
+}
+
+// pageName returns the file name for the page of id
+func pageName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, id)
+}
+
+func (d document) writeSite(dir string) error {
+	tmpl := template.Must(template.New("site").Funcs(docFuncs).Parse(siteTemplates))
+
+	if err := os.MkdirAll(filepath.Join(dir, "messages"), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), []byte(siteStyle), 0o644); err != nil {
+		return err
+	}
+	if err := writeTemplate(filepath.Join(dir, "index.html"), tmpl, "index", d); err != nil {
+		return err
+	}
+	for _, e := range d.Entries {
+		data := struct {
+			Title string
+			docEntry
+		}{d.Title, e}
+		if err := writeTemplate(filepath.Join(dir, "messages", pageName(e.ID)+".html"), tmpl, "message", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTemplate(path string, tmpl *template.Template, name string, data interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := tmpl.ExecuteTemplate(f, name, data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+const siteTemplates = `
+{{define "entryList"}}<table>
+<thead><tr><th>ID</th><th>Severity</th><th>Text</th></tr></thead>
+<tbody>
+{{range .}}<tr><td><a href="{{page .ID}}">{{.ID}}</a></td><td class="severity severity-{{lower .Severity}}">{{.Severity}}</td><td>{{.Text}}</td></tr>
+{{end}}</tbody>
+</table>{{end}}
+
+{{define "index"}}<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.Title}}</title><link rel="stylesheet" href="style.css"></head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="generated">{{len .Entries}} messages, generated {{.Generated}}</p>
+<nav>
+<h2>Components</h2>
+<ul>{{range .Components}}<li><a href="#component-{{.Name}}">{{.Name}}</a> ({{len .Entries}})</li>{{end}}</ul>
+<h2>Severities</h2>
+<ul>{{range .Severities}}<li><a href="#severity-{{lower .Name}}">{{.Name}}</a> ({{len .Entries}})</li>{{end}}</ul>
+</nav>
+<h2>By component</h2>
+{{range .Components}}<h3 id="component-{{.Name}}">{{.Name}}</h3>
+{{template "entryList" .Entries}}
+{{end}}
+<h2>By severity</h2>
+{{range .Severities}}<h3 id="severity-{{lower .Name}}">{{.Name}}</h3>
+{{template "entryList" .Entries}}
+{{end}}
+</body>
+</html>
+{{end}}
+
+{{define "message"}}<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.ID}} - {{.Title}}</title><link rel="stylesheet" href="../style.css"></head>
+<body>
+<p><a href="../index.html">{{.Title}}</a> › <a href="../index.html#component-{{.Component}}">{{.Component}}</a></p>
+<h1>{{.ID}} <span class="severity severity-{{lower .Severity}}">{{.Severity}}</span></h1>
+<p class="text">{{range segments .Text}}{{if .Placeholder}}<var>{{.Text}}</var>{{else}}{{.Text}}{{end}}{{end}}</p>
+{{if .Placeholders}}<h2>Placeholders</h2>
+<ul>{{range .Placeholders}}<li><var>{{.}}</var></li>{{end}}</ul>{{end}}
+{{with .Cause}}<h2>Cause</h2>
+<p>{{.}}</p>{{end}}
+{{with .Recovery}}<h2>Recovery</h2>
+<p>{{.}}</p>{{end}}
+{{if .Replies}}<h2>Replies</h2>
+<ol>{{range .Replies}}<li>{{.}}</li>{{end}}</ol>{{end}}
+{{if .Links}}<h2>See also</h2>
+<ul>{{range .Links}}<li><a href="{{.}}">{{.}}</a></li>{{end}}</ul>{{end}}
+{{if .History}}<h2>History</h2>
+<table>
+<thead><tr><th>Version</th><th>Change</th></tr></thead>
+<tbody>{{range .History}}<tr><td>{{.Version}}</td><td>{{.Note}}</td></tr>{{end}}</tbody>
+</table>{{end}}
+</body>
+</html>
+{{end}}
+`
+
+const siteStyle = `body { font-family: sans-serif; max-width: 60em; margin: 2em auto; padding: 0 1em; line-height: 1.4; }
+table { border-collapse: collapse; width: 100%; }
+th, td { text-align: left; padding: .25em .5em; border-bottom: 1px solid #ddd; vertical-align: top; }
+td:first-child { font-family: monospace; white-space: nowrap; }
+var { font-style: normal; font-family: monospace; background: #eef; padding: 0 .2em; }
+.text { font-size: 1.2em; font-weight: bold; }
+.generated { color: #666; }
+.severity { font-weight: bold; font-size: .8em; }
+.severity-info { color: #0a84c6; }
+.severity-warn { color: #b58900; }
+.severity-error { color: #d0312d; }
+.severity-critical { color: #8b0000; }
+nav ul { list-style: none; padding: 0; }
+nav li { display: inline; margin-right: 1em; }
+`
+
+func (d document) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	esc := render.EscapeMarkdown
+
+	fmt.Fprintf(&b, "# %s\n\n", esc(d.Title))
+	for _, c := range d.Components {
+		fmt.Fprintf(&b, "## %s\n\n", esc(c.Name))
+		for _, e := range c.Entries {
+			fmt.Fprintf(&b, "### %s (%s)\n\n", esc(e.ID), esc(e.Severity))
+			for _, s := range segments(e.Text) {
+				if s.Placeholder {
+					b.WriteString("*" + esc(s.Text) + "*")
+				} else {
+					b.WriteString(esc(s.Text))
+				}
+			}
+			b.WriteString("\n\n")
+			if len(e.Placeholders) > 0 {
+				spans := make([]string, len(e.Placeholders))
+				for i, name := range e.Placeholders {
+					spans[i] = codeSpan(name)
+				}
+				b.WriteString("**Placeholders:** " + strings.Join(spans, ", ") + "\n\n")
+			}
+			if cause := e.Cause(); cause != "" {
+				b.WriteString("**Cause:** " + esc(cause) + "\n\n")
+			}
+			if recovery := e.Recovery(); recovery != "" {
+				b.WriteString("**Recovery:** " + esc(recovery) + "\n\n")
+			}
+			if len(e.Replies) > 0 {
+				b.WriteString("**Replies:**\n\n")
+				for i, r := range e.Replies {
+					fmt.Fprintf(&b, "%d. %s\n", i+1, esc(r))
+				}
+				b.WriteString("\n")
+			}
+			if len(e.Links) > 0 {
+				b.WriteString("**See also:**\n\n")
+				for _, l := range e.Links {
+					if !render.SafeURL(l) || strings.ContainsAny(l, "<> ") {
+						b.WriteString("- " + esc(l) + "\n")
+					} else {
+						b.WriteString("- <" + l + ">\n")
+					}
+				}
+				b.WriteString("\n")
+			}
+			if len(e.History) > 0 {
+				b.WriteString("**History:**\n\n")
+				for _, h := range e.History {
+					b.WriteString("- " + esc(h.Version) + ": " + esc(h.Note) + "\n")
+				}
+				b.WriteString("\n")
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// codeSpan returns s as a Markdown code span, delimited by more backticks
+// than any run in s so that its content is shown literally
+func codeSpan(s string) string {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return fence + s + fence
+}
+
+func (d document) writeMan(w io.Writer) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ".TH OPSMSG-MESSAGES 7 \"%s\" \"opsmsg\" \"%s\"\n", d.Generated, roff(strings.ReplaceAll(d.Title, `"`, "'")))
+	b.WriteString(".SH NAME\nopsmsg-messages \\- " + roff(d.Title) + "\n")
+	for _, c := range d.Components {
+		b.WriteString(".SH " + roff(strings.ToUpper(c.Name)) + "\n")
+		for _, e := range c.Entries {
+			b.WriteString(".SS \"" + roff(e.ID) + " (" + roff(e.Severity) + ")\"\n")
+			for _, s := range segments(e.Text) {
+				if s.Placeholder {
+					b.WriteString("\\fI" + roff(s.Text) + "\\fR")
+				} else {
+					b.WriteString(roff(s.Text))
+				}
+			}
+			b.WriteString("\n")
+			section := func(name, text string) {
+				if text != "" {
+					b.WriteString(".TP\n.B " + name + "\n" + roff(text) + "\n")
+				}
+			}
+			section("Cause", e.Cause())
+			section("Recovery", e.Recovery())
+			for i, r := range e.Replies {
+				section(fmt.Sprintf("Reply %d", i+1), r)
+			}
+			for _, l := range e.Links {
+				section("See also", l)
+			}
+			for _, h := range e.History {
+				section("Version "+roff(h.Version), h.Note)
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// roff escapes text for a man page line
+func roff(s string) string {
+	s = strings.NewReplacer(`\`, `\e`, "-", `\-`, "\n", " ").Replace(s)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
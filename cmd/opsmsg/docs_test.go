@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var docEntries = []catalog.CatalogEntry{
+	{ID: "SRV001", Severity: "INFO", Text: "Server starting", Help: "Cause: Startup. Recovery: None required."},
+	{
+		ID: "SRV002", Severity: "ERROR", Text: "Failed to bind to port {port} on {host}",
+		Help:    "Cause: Another process uses the port. Recovery: Stop it or set a different port.",
+		Replies: []string{"retry", "use port *8081*"},
+		Links:   []string{"https://runbooks.example.com/SRV002", "javascript:alert(1)"},
+		History: []catalog.Change{{Version: "1.2", Note: "Added the host"}},
+	},
+	{
+		ID: "APP007", Severity: "WARN", Text: "Value {a`b} and {``c} of {x_y|z} <b>bold</b>",
+		Help: "No sections, just `help` & text",
+	},
+	{ID: "9LIVES", Severity: "CRITICAL", Text: "# Not a heading"},
+}
+
+func TestDocsMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := newDocument("Messages & *Codes*", sortedEntries(catalog.FromEntries(docEntries))).writeMarkdown(&buf); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", "docs.md.golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("%s differs from the golden file:\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+func TestCodeSpan(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"port", "`port`"},
+		{"a`b", "``a`b``"},
+		{"``c", "``` ``c ```"},
+		{"x_y|z*", "`x_y|z*`"},
+	} {
+		if got := codeSpan(tc.in); got != tc.want {
+			t.Errorf("codeSpan(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDocsCommand(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "messages.7")
+	if code, _, stderr := runCLI(t, "docs", "-format", "man", "-o", out); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	man, err := os.ReadFile(out)
+	if err != nil || !strings.HasPrefix(string(man), ".TH OPSMSG-MESSAGES 7") {
+		t.Errorf("man page %q, %v", man, err)
+	}
+
+	site := filepath.Join(dir, "site")
+	if code, _, stderr := runCLI(t, "docs", "-o", site); code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	for _, name := range []string{"index.html", "style.css", "messages/SRV002.html"} {
+		if _, err := os.Stat(filepath.Join(site, name)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "docs", "-format", "pdf"); code != 2 {
+		t.Errorf("unknown format: exit %d, want 2", code)
+	}
+}
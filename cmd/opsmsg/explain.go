@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/parse"
+)
+
+// idToken matches words that may be message IDs, with an optional
+// severity letter: SRV002, SRV002E. Only IDs found in the catalog count.
+var idToken = regexp.MustCompile(`\b[A-Z][A-Z0-9]*[0-9]{3}[A-Z]?\b`)
+
+// maxBoxLines bounds how far a box is buffered before giving up on it
+const maxBoxLines = 200
+
+// explanation is the catalog information added to a log line
+type explanation struct {
+	ID       string   `json:"id"`
+	Severity string   `json:"severity"`
+	Text     string   `json:"text"`
+	Cause    string   `json:"cause,omitempty"`
+	Recovery string   `json:"recovery,omitempty"`
+	Replies  []string `json:"replies,omitempty"`
+	Links    []string `json:"links,omitempty"`
+}
+
+// runExplain copies a log stream from stdin (or files) to stdout and
+// annotates every line that mentions a catalog ID. Boxes are annotated
+// after their bottom border so they stay intact.
+func runExplain(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("explain", "[file ...]", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	jsonOut := fs.Bool("json", false, "emit JSON lines: JSON input gains an \"explain\" key, other lines become {\"line\": ...}")
+	once := fs.Bool("once", false, "explain each ID only the first time it appears")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg explain: %v\n", err)
+		return 1
+	}
+
+	e := explainer{cat: cat, json: *jsonOut, once: *once, seen: make(map[string]bool)}
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+
+	inputs := fs.Args()
+	if len(inputs) == 0 {
+		inputs = []string{"-"}
+	}
+	for _, name := range inputs {
+		if err := e.runFile(name, out); err != nil {
+			fmt.Fprintf(stderr, "opsmsg explain: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+type explainer struct {
+	cat  catalog.Catalog
+	json bool
+	once bool
+	seen map[string]bool
+}
+
+// runFile explains the file name, or stdin for "-", closing the file
+// before the next one is opened
+func (e *explainer) runFile(name string, w *bufio.Writer) error {
+	if name == "-" {
+		return e.run(os.Stdin, w)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return e.run(f, w)
+}
+
+func (e *explainer) run(r io.Reader, w *bufio.Writer) error {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	var box []string
+	for lines.Scan() {
+		line := lines.Text()
+		plain := strings.TrimSpace(parse.StripANSI(line))
+
+		if box != nil {
+			box = append(box, line)
+			if strings.HasPrefix(plain, "╰") || len(box) > maxBoxLines {
+				e.emit(w, box)
+				box = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(plain, "╭") {
+			box = []string{line}
+			continue
+		}
+		e.emit(w, []string{line})
+		// Keep up with streams such as tail -f
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if box != nil {
+		e.emit(w, box)
+	}
+	return lines.Err()
+}
+
+// emit writes a group of lines followed by the explanations of the IDs
+// they mention
+func (e *explainer) emit(w *bufio.Writer, group []string) {
+	var found []explanation
+	for _, line := range group {
+		found = append(found, e.explain(line)...)
+	}
+
+	if e.json {
+		// A box becomes one record
+		writeJSONLine(w, strings.Join(group, "\n"), found)
+		return
+	}
+
+	for _, line := range group {
+		fmt.Fprintln(w, line)
+	}
+	for _, x := range found {
+		fmt.Fprintf(w, "  ↳ %s (%s) %s\n", x.ID, x.Severity, x.Text)
+		if x.Cause != "" {
+			fmt.Fprintf(w, "    Cause: %s\n", x.Cause)
+		}
+		if x.Recovery != "" {
+			fmt.Fprintf(w, "    Recovery: %s\n", x.Recovery)
+		}
+		if len(x.Replies) > 0 {
+			fmt.Fprintln(w, "    Reply with:")
+			for i, reply := range x.Replies {
+				fmt.Fprintf(w, "      %d. %s\n", i+1, reply)
+			}
+		}
+		for _, link := range x.Links {
+			fmt.Fprintf(w, "    See: %s\n", link)
+		}
+	}
+}
+
+// explain returns the catalog entries for the IDs in line, each once
+func (e *explainer) explain(line string) []explanation {
+	var found []explanation
+	local := make(map[string]bool)
+	for _, token := range idToken.FindAllString(parse.StripANSI(line), -1) {
+		entry, ok := e.cat.Lookup(token)
+		if !ok || local[entry.ID] || (e.once && e.seen[entry.ID]) {
+			continue
+		}
+		local[entry.ID] = true
+		e.seen[entry.ID] = true
+		found = append(found, explanation{
+			ID:       entry.ID,
+			Severity: entry.Severity,
+			Text:     entry.Text,
+			Cause:    entry.Cause(),
+			Recovery: entry.Recovery(),
+			Replies:  entry.Replies,
+			Links:    entry.Links,
+		})
+	}
+	return found
+}
+
+// writeJSONLine writes line as one JSON object. JSON objects are kept
+// byte for byte with an "explain" key appended; other text is wrapped as
+// {"line": ...} without color codes.
+func writeJSONLine(w io.Writer, line string, found []explanation) {
+	extra := ""
+	if len(found) > 0 {
+		data, _ := json.Marshal(found)
+		extra = `"explain":` + string(data)
+	}
+
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		if extra == "" {
+			fmt.Fprintln(w, trimmed)
+			return
+		}
+		body := strings.TrimSpace(strings.TrimSuffix(trimmed, "}"))
+		if body != "{" {
+			extra = "," + extra
+		}
+		fmt.Fprintln(w, body+extra+"}")
+		return
+	}
+
+	if extra != "" {
+		extra = "," + extra
+	}
+	quoted, _ := json.Marshal(parse.StripANSI(line))
+	fmt.Fprintln(w, `{"line":`+string(quoted)+extra+"}")
+}
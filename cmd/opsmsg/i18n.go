@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/i18n"
+)
+
+// runI18n moves catalog text and help to and from translators
+func runI18n(args []string, stdout, stderr io.Writer) int {
+	usage := func() {
+		fmt.Fprintln(stderr, "Usage: opsmsg i18n <export|import|report> [flags] [arguments]")
+	}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	switch args[0] {
+	case "export":
+		return runI18nExport(args[1:], stdout, stderr)
+	case "import":
+		return runI18nImport(args[1:], stdout, stderr)
+	case "report":
+		return runI18nReport(args[1:], stdout, stderr)
+	case "-h", "-help", "help":
+		usage()
+		return 0
+	}
+	fmt.Fprintf(stderr, "opsmsg i18n: unknown subcommand %q\n", args[0])
+	usage()
+	return 2
+}
+
+// runI18nExport writes the strings to translate as PO or XLIFF
+func runI18nExport(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("i18n export", "", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	format := fs.String("format", "po", "output `format`: po, xliff12 or xliff20")
+	lang := fs.String("lang", "", "target language `tag`, such as de")
+	sourceLang := fs.String("source-lang", "en", "language `tag` of the catalogs")
+	locale := fs.String("locale", "", "locale catalog `file` whose translations fill the targets")
+	out := fs.String("o", "", "write to `file` instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	base, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n export: %v\n", err)
+		return 1
+	}
+	var translation catalog.Catalog
+	if *locale != "" {
+		if translation, err = catalog.Load(*locale); err != nil {
+			fmt.Fprintf(stderr, "opsmsg i18n export: %v\n", err)
+			return 1
+		}
+	}
+	units := i18n.Units(base, translation)
+
+	var buf bytes.Buffer
+	switch *format {
+	case "po":
+		err = i18n.WritePO(&buf, units, *lang)
+	case "xliff12":
+		err = i18n.WriteXLIFF12(&buf, units, *sourceLang, *lang)
+	case "xliff20":
+		err = i18n.WriteXLIFF20(&buf, units, *sourceLang, *lang)
+	default:
+		fmt.Fprintf(stderr, "opsmsg i18n export: unknown format %q\n", *format)
+		return 2
+	}
+	if err == nil {
+		err = writeOutput(*out, stdout, buf.Bytes())
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n export: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// runI18nImport merges translated PO or XLIFF files into a locale
+// catalog, written in the format its extension names. Rejected
+// translations are reported; errors make it exit 1.
+func runI18nImport(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("i18n import", "file.po|file.xlf ...", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	out := fs.String("o", "", "locale catalog `file` to update; created when missing")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() == 0 || *out == "" {
+		fs.Usage()
+		return 2
+	}
+
+	base, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n import: %v\n", err)
+		return 1
+	}
+	translation, err := catalog.Load(*out)
+	if err != nil && !os.IsNotExist(err) {
+		fmt.Fprintf(stderr, "opsmsg i18n import: %v\n", err)
+		return 1
+	}
+
+	status := 0
+	for _, path := range fs.Args() {
+		file, err := readTranslations(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "opsmsg i18n import: %s: %v\n", path, err)
+			return 1
+		}
+		var problems []catalog.Problem
+		translation, problems = i18n.Apply(base, translation, file.Units)
+		for _, p := range problems {
+			fmt.Fprintf(stderr, "%s: %s\n", path, p)
+			if !p.Warning {
+				status = 1
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := i18n.WriteCatalog(&buf, translation, catalog.FormatOf(*out)); err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n import: %v\n", err)
+		return 1
+	}
+	if err := writeOutput(*out, stdout, buf.Bytes()); err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n import: %v\n", err)
+		return 1
+	}
+	return status
+}
+
+// readTranslations reads a PO file, or XLIFF for .xlf and .xliff
+func readTranslations(path string) (i18n.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return i18n.File{}, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".po", ".pot":
+		return i18n.ReadPO(f)
+	case ".xlf", ".xliff":
+		return i18n.ReadXLIFF(f)
+	}
+	return i18n.File{}, fmt.Errorf("unknown file type; want .po, .xlf or .xliff")
+}
+
+// runI18nReport prints how complete each locale catalog is. It exits 1
+// when a locale has broken translations or is below -min percent.
+func runI18nReport(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("i18n report", "tag=file ...", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	min := fs.Float64("min", 0, "fail when a locale has fewer than `percent` of strings translated")
+	verbose := fs.Bool("v", false, "list missing, invalid and obsolete entries")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	base, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg i18n report: %v\n", err)
+		return 1
+	}
+
+	var reports []i18n.Report
+	for _, arg := range fs.Args() {
+		tag, path, ok := strings.Cut(arg, "=")
+		if !ok || tag == "" {
+			fmt.Fprintf(stderr, "opsmsg i18n report: %q: want tag=file\n", arg)
+			return 2
+		}
+		translation, err := catalog.Load(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "opsmsg i18n report: %v\n", err)
+			return 1
+		}
+		reports = append(reports, i18n.Check(tag, base, translation))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Locale < reports[j].Locale })
+
+	status := 0
+	fmt.Fprintf(stdout, "%-8s %10s %7s %8s %8s %9s\n", "LOCALE", "TRANSLATED", "TOTAL", "PERCENT", "INVALID", "OBSOLETE")
+	for _, r := range reports {
+		fmt.Fprintf(stdout, "%-8s %10d %7d %7.1f%% %8d %9d\n", r.Locale, r.Translated, r.Total, r.Percent(), len(r.Invalid), len(r.Obsolete))
+		if len(r.Invalid) > 0 || r.Percent() < *min {
+			status = 1
+		}
+	}
+	if *verbose {
+		for _, r := range reports {
+			for _, list := range []struct {
+				name string
+				keys []string
+			}{{"missing", r.Missing}, {"invalid", r.Invalid}, {"obsolete", r.Obsolete}} {
+				for _, key := range list.keys {
+					fmt.Fprintf(stdout, "%s: %s: %s\n", r.Locale, list.name, key)
+				}
+			}
+		}
+	}
+	return status
+}
+
+// writeOutput writes data to path, or to stdout when path is empty
+func writeOutput(path string, stdout io.Writer, data []byte) error {
+	if path == "" {
+		_, err := stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
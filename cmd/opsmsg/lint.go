@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+// runLint validates catalog files. It exits with 1 when there are errors,
+// or warnings with -strict, so it can gate CI.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("lint", "[file ...]", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	strict := fs.Bool("strict", false, "treat warnings as errors")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+
+	// Files named on the command line are linted on their own; without
+	// any, the selected catalogs are
+	files := append(fs.Args(), cf.files()...)
+	lintBuiltin := len(files) == 0 && cf.builtin
+
+	errors, warnings := 0, 0
+	report := func(name string, entries []catalog.CatalogEntry) {
+		for _, p := range catalog.Validate(entries) {
+			fmt.Fprintf(stdout, "%s: %s\n", name, p)
+			if p.Warning {
+				warnings++
+			} else {
+				errors++
+			}
+		}
+	}
+
+	if lintBuiltin {
+		report("builtin", catalog.BuiltinEntries())
+	}
+	for _, path := range files {
+		entries, err := catalog.LoadEntries(path)
+		if err != nil {
+			fmt.Fprintf(stdout, "%s: error: %v\n", path, err)
+			errors++
+			continue
+		}
+		report(path, entries)
+	}
+
+	if errors > 0 || warnings > 0 {
+		fmt.Fprintf(stderr, "%d error(s), %d warning(s)\n", errors, warnings)
+	}
+	if errors > 0 || (*strict && warnings > 0) {
+		return 1
+	}
+	return 0
+}
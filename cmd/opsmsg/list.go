@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+)
+
+func runList(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("list", "", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	severity := fs.String("severity", "", "only show these comma-separated `severities`")
+	prefix := fs.String("prefix", "", "only show IDs starting with `prefix`")
+	format := fs.String("format", "table", "output `format`: table or json")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	sevs, err := parseSeverities(*severity)
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg list: %v\n", err)
+		return 2
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg list: %v\n", err)
+		return 1
+	}
+
+	var entries []catalog.CatalogEntry
+	for _, e := range sortedEntries(cat) {
+		if sevs != nil && !sevs[message.Severity(e.Severity)] {
+			continue
+		}
+		if !strings.HasPrefix(e.ID, strings.ToUpper(*prefix)) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := writeEntries(stdout, entries, *format); err != nil {
+		fmt.Fprintf(stderr, "opsmsg list: %v\n", err)
+		return 2
+	}
+	return 0
+}
+
+// writeEntries prints entries as an aligned table or a JSON array
+func writeEntries(w io.Writer, entries []catalog.CatalogEntry, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSEVERITY\tTEXT")
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", e.ID, e.Severity, e.Text)
+		}
+		return tw.Flush()
+	case "json":
+		if entries == nil {
+			entries = []catalog.CatalogEntry{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
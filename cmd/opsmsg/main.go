@@ -0,0 +1,80 @@
+// Command opsmsg works with message catalogs from the command line: it
+// lints catalog files, lists and searches their entries and shows how a
+// message renders.
+//
+//	opsmsg lint custom.yaml
+//	opsmsg list -severity ERROR,CRITICAL
+//	opsmsg show DEP005
+//	opsmsg search -c custom.yaml timeout
+//	kubectl logs api | opsmsg explain
+//	kubectl logs -f api | opsmsg view -severity ERROR,CRITICAL
+//	opsmsg serve -addr :8080 -locale de=catalog.de.yaml
+//	opsmsg i18n export -format xliff20 -lang de -o catalog.de.xlf
+//	opsmsg coverage -c custom.yaml ./...
+//
+// Catalogs are given with -c, which may be repeated; later catalogs
+// override entries of earlier ones. The built-in catalog is loaded first
+// unless -builtin=false is set. OPSMSG_CATALOG lists default catalog
+// files when -c is not used.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command is an opsmsg subcommand. run returns the exit status.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"lint", "check catalog files for errors", runLint},
+		{"list", "list catalog entries", runList},
+		{"show", "render one message", runShow},
+		{"search", "search message text and help", runSearch},
+		{"explain", "annotate a log stream with catalog help", runExplain},
+		{"docs", "generate HTML, Markdown or man page documentation", runDocs},
+		{"diff", "compare two catalog versions for breaking changes", runDiff},
+		{"view", "browse a live log stream interactively", runView},
+		{"serve", "serve the catalog as a REST API and in the browser", runServe},
+		{"i18n", "export and import translations as PO or XLIFF", runI18n},
+		{"coverage", "report catalog IDs used and missing in Go source", runCoverage},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "-help" || args[0] == "help" {
+		usage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout, stderr)
+		}
+	}
+	fmt.Fprintf(stderr, "opsmsg: unknown command %q\n\n", args[0])
+	usage(stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: opsmsg <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "opsmsg <command> -h" for the flags of a command.`)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+const testCatalog = `- id: APP001
+  severity: INFO
+  text: "Worker {worker} started"
+  help: "Cause: The pool grew. Recovery: None required."
+- id: APP002
+  severity: ERROR
+  text: "Queue {queue} is full"
+  help: "Cause: Consumers are too slow. Recovery: Add consumers or raise the queue timeout."
+  replies: ["retry", "drop"]
+- id: app.legacy3
+  severity: WARN
+  text: "Legacy endpoint called"
+  help: "Cause: An old client. Recovery: Upgrade it."
+`
+
+// runCLI runs opsmsg with args and returns its exit status and output
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("OPSMSG_CATALOG", "")
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func testCatalogFile(t *testing.T) string {
+	t.Helper()
+	return filepath.Join(writeTree(t, map[string]string{"app.yaml": testCatalog}), "app.yaml")
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"help"}, {"frobnicate"}} {
+		code, _, stderr := runCLI(t, args...)
+		if code != 2 || !strings.Contains(stderr, "Usage: opsmsg <command>") {
+			t.Errorf("%v: exit %d, stderr:\n%s", args, code, stderr)
+		}
+	}
+	if code, _, _ := runCLI(t, "list", "-h"); code != 0 {
+		t.Errorf("list -h: exit %d, want 0", code)
+	}
+	if code, _, _ := runCLI(t, "list", "-bogus"); code != 2 {
+		t.Errorf("list -bogus: exit %d, want 2", code)
+	}
+}
+
+func TestLint(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"good.yaml":   testCatalog,
+		"warn.yaml":   "- id: APP010\n  severity: INFO\n  text: No help\n",
+		"bad.yaml":    "- id: APP020\n  severity: LOUD\n  text: \"Broken {brace\"\n  help: h\n",
+		"syntax.yaml": "- id: [",
+	})
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, tc := range []struct {
+		args   []string
+		code   int
+		stdout []string
+	}{
+		{[]string{}, 0, nil},
+		{[]string{path("good.yaml")}, 0, []string{"app.legacy3: warning: id does not look like ABC123"}},
+		{[]string{path("warn.yaml")}, 0, []string{"APP010: warning: missing help"}},
+		{[]string{"-strict", path("warn.yaml")}, 1, []string{"missing help"}},
+		{[]string{path("bad.yaml")}, 1, []string{`APP020: error: unknown severity "LOUD"`, "APP020: error: text:"}},
+		{[]string{path("syntax.yaml")}, 1, []string{path("syntax.yaml") + ": error:"}},
+		{[]string{"-c", path("bad.yaml")}, 1, []string{"APP020"}},
+	} {
+		code, stdout, stderr := runCLI(t, append([]string{"lint"}, tc.args...)...)
+		if code != tc.code {
+			t.Errorf("lint %v: exit %d, want %d\nstdout:\n%s\nstderr:\n%s", tc.args, code, tc.code, stdout, stderr)
+		}
+		for _, want := range tc.stdout {
+			if !strings.Contains(stdout, want) {
+				t.Errorf("lint %v: stdout lacks %q:\n%s", tc.args, want, stdout)
+			}
+		}
+		if len(tc.stdout) == 0 && stdout != "" {
+			t.Errorf("lint %v: unexpected output:\n%s", tc.args, stdout)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	path := testCatalogFile(t)
+
+	code, stdout, stderr := runCLI(t, "list", "-builtin=false", "-c", path)
+	want := "ID           SEVERITY  TEXT\n" +
+		"APP001       INFO      Worker {worker} started\n" +
+		"APP002       ERROR     Queue {queue} is full\n" +
+		"app.legacy3  WARN      Legacy endpoint called\n"
+	if code != 0 || stdout != want {
+		t.Errorf("exit %d, stdout:\n%s\nwant:\n%s\nstderr:\n%s", code, stdout, want, stderr)
+	}
+
+	code, stdout, _ = runCLI(t, "list", "-c", path, "-severity", "error,critical", "-prefix", "app", "-format", "json")
+	var entries []catalog.CatalogEntry
+	if err := json.Unmarshal([]byte(stdout), &entries); err != nil {
+		t.Fatalf("exit %d: %v\n%s", code, err, stdout)
+	}
+	if len(entries) != 1 || entries[0].ID != "APP002" || len(entries[0].Replies) != 2 {
+		t.Errorf("filtered to %+v", entries)
+	}
+
+	// The built-in catalog is loaded too unless -builtin=false
+	if _, stdout, _ = runCLI(t, "list", "-c", path, "-prefix", "SRV"); !strings.Contains(stdout, "SRV002") {
+		t.Errorf("built-in entries missing:\n%s", stdout)
+	}
+
+	for _, args := range [][]string{
+		{"-severity", "LOUD"},
+		{"-format", "xml"},
+		{"extra"},
+	} {
+		if code, _, _ := runCLI(t, append([]string{"list"}, args...)...); code != 2 {
+			t.Errorf("list %v: exit %d, want 2", args, code)
+		}
+	}
+	if code, _, stderr := runCLI(t, "list", "-c", filepath.Join(t.TempDir(), "missing.yaml")); code != 1 || !strings.HasPrefix(stderr, "opsmsg list: ") {
+		t.Errorf("missing catalog: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestShow(t *testing.T) {
+	path := testCatalogFile(t)
+
+	for _, tc := range []struct {
+		id   string
+		want string
+	}{
+		{"APP002", "APP002"},
+		{"app002", "APP002"},
+		{"APP002E", "APP002"},
+		{"app.legacy3", "app.legacy3"},
+	} {
+		code, stdout, stderr := runCLI(t, "show", "-c", path, "-no-color", "-width", "60", "-set", "queue=jobs", tc.id)
+		if code != 0 {
+			t.Errorf("show %s: exit %d, stderr %q", tc.id, code, stderr)
+			continue
+		}
+		if !strings.Contains(stdout, "] "+tc.want+" ") || strings.Contains(stdout, "\x1b[") {
+			t.Errorf("show %s:\n%s", tc.id, stdout)
+		}
+		if tc.want == "APP002" && !strings.Contains(stdout, "Queue jobs is full") {
+			t.Errorf("show %s did not fill the placeholder:\n%s", tc.id, stdout)
+		}
+	}
+
+	for _, tc := range []struct {
+		args []string
+		code int
+	}{
+		{[]string{"-c", path, "APP999"}, 1},
+		{[]string{"-c", path, "APP002I"}, 1},
+		{[]string{"-c", path, "-width", "10", "APP002"}, 2},
+		{[]string{"-c", path, "-set", "queue", "APP002"}, 2},
+		{[]string{"APP001", "APP002"}, 2},
+	} {
+		if code, _, stderr := runCLI(t, append([]string{"show"}, tc.args...)...); code != tc.code {
+			t.Errorf("show %v: exit %d, want %d; stderr %q", tc.args, code, tc.code, stderr)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	path := testCatalogFile(t)
+
+	for _, tc := range []struct {
+		terms []string
+		code  int
+		ids   []string
+	}{
+		{[]string{"QUEUE"}, 0, []string{"APP002"}},
+		{[]string{"consumers", "timeout"}, 0, []string{"APP002"}},
+		{[]string{"app0"}, 0, []string{"APP001", "APP002"}},
+		{[]string{"queue", "legacy"}, 1, nil},
+	} {
+		code, stdout, stderr := runCLI(t, append([]string{"search", "-builtin=false", "-c", path, "-format", "json"}, tc.terms...)...)
+		if code != tc.code {
+			t.Errorf("search %v: exit %d, want %d; stderr %q", tc.terms, code, tc.code, stderr)
+		}
+		var entries []catalog.CatalogEntry
+		if err := json.Unmarshal([]byte(stdout), &entries); err != nil {
+			t.Fatalf("search %v: %v\n%s", tc.terms, err, stdout)
+		}
+		var ids []string
+		for _, e := range entries {
+			ids = append(ids, e.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.ids, ",") {
+			t.Errorf("search %v found %v, want %v", tc.terms, ids, tc.ids)
+		}
+	}
+
+	if code, _, _ := runCLI(t, "search"); code != 2 {
+		t.Errorf("search without terms: exit %d, want 2", code)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+// runSearch lists the entries whose ID, text or help contain every search
+// term, ignoring case
+func runSearch(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("search", "term ...", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	format := fs.String("format", "table", "output `format`: table or json")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg search: %v\n", err)
+		return 1
+	}
+
+	var entries []catalog.CatalogEntry
+	for _, e := range sortedEntries(cat) {
+		if matches(e, fs.Args()) {
+			entries = append(entries, e)
+		}
+	}
+	if err := writeEntries(stdout, entries, *format); err != nil {
+		fmt.Fprintf(stderr, "opsmsg search: %v\n", err)
+		return 2
+	}
+	if len(entries) == 0 {
+		return 1
+	}
+	return 0
+}
+
+func matches(e catalog.CatalogEntry, terms []string) bool {
+	haystack := strings.ToLower(e.ID + "\n" + e.Text + "\n" + e.Help)
+	for _, term := range terms {
+		if !strings.Contains(haystack, strings.ToLower(term)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/api"
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/webui"
+)
+
+// runServe serves the catalog REST API, with the catalog browser under
+// /ui/, until interrupted
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("serve", "", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	addr := fs.String("addr", "localhost:8080", "listen `address`")
+	var locales stringList
+	fs.Var(&locales, "locale", "translated catalog, as `tag=file` such as de=catalog.de.yaml; may be repeated")
+	defaultLocale := fs.String("default-locale", "en", "language `tag` of the catalogs given with -c")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg serve: %v\n", err)
+		return 1
+	}
+	h := api.NewHandler(cat)
+	h.DefaultLocale = *defaultLocale
+	for _, l := range locales {
+		tag, path, ok := strings.Cut(l, "=")
+		if !ok || tag == "" {
+			fmt.Fprintf(stderr, "opsmsg serve: -locale %q: want tag=file\n", l)
+			return 2
+		}
+		translation, err := catalog.Load(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "opsmsg serve: %v\n", err)
+			return 1
+		}
+		h.AddLocale(tag, translation)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/", h)
+	mux.Handle("/ui/", http.StripPrefix("/ui", webui.NewConsole(cat, nil)))
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(stderr, "opsmsg serve: listening on http://%s (API at /messages, browser at /ui/)\n", *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(stderr, "opsmsg serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/render"
+)
+
+// runShow renders one message with the box layout. The ID may be written
+// with a severity letter (DEP005E) and placeholders can be filled with
+// -set.
+func runShow(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("show", "ID", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	width := fs.Int("width", 80, fmt.Sprintf("box `width`, at least %d", render.MinBoxWidth))
+	noColor := fs.Bool("no-color", false, "disable colors")
+	var sets stringList
+	fs.Var(&sets, "set", "fill a placeholder, as `key=value`; may be repeated")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if *width < render.MinBoxWidth {
+		fmt.Fprintf(stderr, "opsmsg show: -width %d is too narrow; the minimum is %d\n", *width, render.MinBoxWidth)
+		return 2
+	}
+
+	cat, err := cf.load()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg show: %v\n", err)
+		return 1
+	}
+	// IDs are usually upper case, but custom catalogs may use others
+	entry, ok := cat.Lookup(fs.Arg(0))
+	if !ok {
+		entry, ok = cat.Lookup(strings.ToUpper(fs.Arg(0)))
+	}
+	if !ok {
+		fmt.Fprintf(stderr, "opsmsg show: unknown message ID %q\n", fs.Arg(0))
+		return 1
+	}
+
+	ctx := make(map[string]string)
+	for _, kv := range sets {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			fmt.Fprintf(stderr, "opsmsg show: -set %q: want key=value\n", kv)
+			return 2
+		}
+		ctx[k] = v
+	}
+
+	msg := cat.New(entry.ID, ctx)
+	msg.Timestamp = time.Now()
+	r := render.Box{Options: render.Options{DisableColors: *noColor}, Width: *width}
+	if err := r.Render(stdout, msg); err != nil {
+		fmt.Fprintf(stderr, "opsmsg show: %v\n", err)
+		return 1
+	}
+	return 0
+}
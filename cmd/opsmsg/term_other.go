@@ -0,0 +1,27 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// terminal is not implemented on this platform
+type terminal struct{}
+
+func openTerminal() (*terminal, error) {
+	return nil, errors.New("interactive terminals are not supported on this platform")
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	return 0, errors.New("no terminal")
+}
+
+func (t *terminal) size() (int, int) {
+	return 80, 24
+}
+
+func (t *terminal) restore() {}
+
+func notifyResize(c chan<- os.Signal) {}
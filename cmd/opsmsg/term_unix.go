@@ -0,0 +1,69 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/unix"
+)
+
+// terminal is the controlling terminal switched to raw mode, so keys are
+// read one at a time without echo
+type terminal struct {
+	tty   *os.File
+	saved unix.Termios
+}
+
+// openTerminal opens /dev/tty, which works even when stdin is a pipe,
+// and puts it into raw mode
+func openTerminal() (*terminal, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	fd := int(tty.Fd())
+	saved, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		tty.Close()
+		return nil, err
+	}
+
+	raw := *saved
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		tty.Close()
+		return nil, err
+	}
+	return &terminal{tty: tty, saved: *saved}, nil
+}
+
+func (t *terminal) Read(p []byte) (int, error) {
+	return t.tty.Read(p)
+}
+
+// size returns the number of columns and rows (default: 80x24)
+func (t *terminal) size() (int, int) {
+	ws, err := unix.IoctlGetWinsize(int(t.tty.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Col == 0 || ws.Row == 0 {
+		return 80, 24
+	}
+	return int(ws.Col), int(ws.Row)
+}
+
+// restore leaves raw mode and closes the terminal
+func (t *terminal) restore() {
+	unix.IoctlSetTermios(int(t.tty.Fd()), ioctlSetTermios, &t.saved)
+	t.tty.Close()
+}
+
+// notifyResize sends to c when the terminal window changes size
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, unix.SIGWINCH)
+}
@@ -0,0 +1,743 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/parse"
+	"github.com/martencassel/opsmsg/render"
+)
+
+const viewKeys = "q quit  / search  n/N next/prev  & grep  i id  s severity  c clear  h help  o first  f follow"
+
+// runView is an interactive viewer for a stream of opsmsg output read
+// from a file or stdin. Keys are read from the terminal, so output can be
+// piped in: kubectl logs -f api | opsmsg view
+func runView(args []string, stdout, stderr io.Writer) int {
+	fs := newFlagSet("view", "[file]", stderr)
+	var cf catalogFlags
+	cf.register(fs)
+	severities := fs.String("severity", "", "show only these comma-separated `severities`")
+	ids := fs.String("id", "", "show only these comma-separated `IDs` or ID prefixes")
+	grep := fs.String("grep", "", "show only messages matching `regexp`")
+	tail := fs.Bool("f", false, "keep reading the file as it grows, like tail -f")
+	keep := fs.Int("keep", 10000, "keep the newest `n` messages, dropping older ones")
+	noColor := fs.Bool("no-color", false, "disable colors")
+	if err := fs.Parse(args); err != nil {
+		return parseError(err)
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+	if *keep < 1 {
+		fmt.Fprintf(stderr, "opsmsg view: -keep %d must be at least 1\n", *keep)
+		return 2
+	}
+
+	v := &viewer{follow: true, theme: render.DefaultTheme, keep: *keep}
+	var err error
+	if v.severities, err = parseSeverities(*severities); err != nil {
+		fmt.Fprintf(stderr, "opsmsg view: %v\n", err)
+		return 2
+	}
+	v.ids = splitList(*ids)
+	if *grep != "" {
+		if v.grep, err = regexp.Compile(*grep); err != nil {
+			fmt.Fprintf(stderr, "opsmsg view: -grep: %v\n", err)
+			return 2
+		}
+	}
+	if v.cat, err = cf.load(); err != nil {
+		fmt.Fprintf(stderr, "opsmsg view: %v\n", err)
+		return 1
+	}
+
+	out, ok := stdout.(*os.File)
+	if !ok || !isCharDevice(out) {
+		fmt.Fprintln(stderr, "opsmsg view: output is not a terminal")
+		return 1
+	}
+	if !*noColor {
+		v.mode = render.DetectColorMode(out)
+	}
+	if render.DetectHyperlinks(out) {
+		v.links = render.HyperlinksOn
+	} else {
+		v.links = render.HyperlinksOff
+	}
+
+	in := io.Reader(os.Stdin)
+	v.source = "stdin"
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "opsmsg view: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in, v.source = f, fs.Arg(0)
+		if *tail {
+			in = tailReader{f}
+		}
+	} else if isCharDevice(os.Stdin) {
+		fmt.Fprintln(stderr, "opsmsg view: no input; name a file or pipe output in")
+		return 2
+	}
+
+	term, err := openTerminal()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg view: %v\n", err)
+		return 1
+	}
+
+	w := bufio.NewWriter(out)
+	// Switch to the alternate screen and hide the cursor while running
+	w.WriteString("\x1b[?1049h\x1b[?25l")
+	err = v.run(term, in, w)
+	w.WriteString("\x1b[?25h\x1b[?1049l")
+	w.Flush()
+	term.restore()
+	if err != nil {
+		fmt.Fprintf(stderr, "opsmsg view: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// run reads messages and keys until the user quits
+func (v *viewer) run(term *terminal, in io.Reader, w *bufio.Writer) error {
+	msgs := make(chan message.Message, 256)
+	done := make(chan error, 1)
+	go func() {
+		s := parse.NewScanner(in)
+		for s.Scan() {
+			msgs <- s.Message()
+		}
+		done <- s.Err()
+	}()
+
+	keys := make(chan string)
+	go readKeys(term, keys)
+
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+	v.cols, v.rows = term.size()
+
+	for {
+		v.draw(w)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		select {
+		case k, ok := <-keys:
+			if !ok || !v.key(k) {
+				return nil
+			}
+		case msg := <-msgs:
+			v.add(msg)
+			// Take everything already waiting before redrawing
+			for more := true; more; {
+				select {
+				case msg := <-msgs:
+					v.add(msg)
+				default:
+					more = false
+				}
+			}
+		case err := <-done:
+			v.eof = true
+			if err != nil {
+				v.status = "read error: " + err.Error()
+			}
+			done = nil
+		case <-resize:
+			v.cols, v.rows = term.size()
+		}
+	}
+}
+
+// viewer is the state of the view command
+type viewer struct {
+	cat    catalog.Catalog
+	theme  *render.Theme
+	mode   render.ColorMode
+	links  render.HyperlinkMode
+	source string
+
+	msgs   []message.Message
+	keep   int   // the most messages kept, 0 for no limit
+	shown  []int // indexes into msgs that pass the filters
+	cursor int   // index into shown
+	top    int   // first visible index into shown
+	follow bool  // keep the newest message selected
+	unseen int   // messages that arrived while paused
+	help   bool  // show the help panel
+	eof    bool
+
+	severities map[message.Severity]bool
+	ids        []string
+	grep       *regexp.Regexp
+	search     *regexp.Regexp
+
+	// prompt is the label of the line being edited, "" when none
+	prompt string
+	input  []rune
+	// status is shown in the bottom line until the next key
+	status string
+
+	cols, rows int
+}
+
+// severityLevels are the known severities from least to most severe
+var severityLevels = []message.Severity{message.Info, message.Warn, message.Error, message.Critical}
+
+func (v *viewer) add(msg message.Message) {
+	v.msgs = append(v.msgs, msg)
+	defer v.trim()
+	if !v.match(msg) {
+		return
+	}
+	v.shown = append(v.shown, len(v.msgs)-1)
+	if v.follow {
+		v.cursor = len(v.shown) - 1
+	} else {
+		v.unseen++
+	}
+}
+
+// trim drops the oldest messages beyond the keep limit, moving the
+// selection along with the messages that remain
+func (v *viewer) trim() {
+	drop := len(v.msgs) - v.keep
+	if v.keep <= 0 || drop <= 0 {
+		return
+	}
+	v.msgs = v.msgs[drop:]
+	gone := 0
+	for gone < len(v.shown) && v.shown[gone] < drop {
+		gone++
+	}
+	v.shown = v.shown[gone:]
+	for i := range v.shown {
+		v.shown[i] -= drop
+	}
+	v.cursor = max(v.cursor-gone, 0)
+	v.top = max(v.top-gone, 0)
+	v.unseen = min(v.unseen, len(v.shown))
+}
+
+// match reports whether msg passes the filters
+func (v *viewer) match(msg message.Message) bool {
+	if v.severities != nil && !v.severities[msg.Severity] {
+		return false
+	}
+	if len(v.ids) > 0 {
+		found := false
+		for _, id := range v.ids {
+			if strings.HasPrefix(msg.ID, id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return v.grep == nil || v.grep.MatchString(searchText(msg))
+}
+
+// searchText is what -grep and / match against
+func searchText(msg message.Message) string {
+	return msg.ID + " " + string(msg.Severity) + " " + msg.Render()
+}
+
+// refilter recomputes the shown messages, keeping the selected message
+// or the one after it selected
+func (v *viewer) refilter() {
+	selected := -1
+	if v.cursor < len(v.shown) {
+		selected = v.shown[v.cursor]
+	}
+	v.shown = v.shown[:0]
+	v.cursor = 0
+	for i, msg := range v.msgs {
+		if !v.match(msg) {
+			continue
+		}
+		if i <= selected {
+			v.cursor = len(v.shown)
+		}
+		v.shown = append(v.shown, i)
+	}
+	if v.follow {
+		v.end()
+	}
+}
+
+// key handles one key press and returns false to quit
+func (v *viewer) key(k string) bool {
+	v.status = ""
+	if v.prompt != "" {
+		v.edit(k)
+		return true
+	}
+
+	switch k {
+	case "q", "ctrl-c":
+		return false
+	case "down", "j":
+		v.move(1)
+	case "up", "k":
+		v.move(-1)
+	case "pgdn", " ":
+		v.move(v.listRows())
+	case "pgup", "b":
+		v.move(-v.listRows())
+	case "home", "g":
+		v.move(-len(v.shown))
+	case "end", "G":
+		v.end()
+	case "f":
+		if v.follow {
+			v.follow = false
+		} else {
+			v.end()
+		}
+	case "h", "enter":
+		v.help = !v.help
+	case "o":
+		v.first()
+	case "/", "&", "i":
+		v.prompt = map[string]string{"/": "search: ", "&": "grep: ", "i": "id: "}[k]
+		v.input = v.input[:0]
+	case "n":
+		v.find(1)
+	case "N":
+		v.find(-1)
+	case "s":
+		v.cycleSeverity()
+	case "c":
+		v.severities, v.ids, v.grep = nil, nil, nil
+		v.refilter()
+	}
+	return true
+}
+
+// edit handles a key while a prompt is open
+func (v *viewer) edit(k string) {
+	switch k {
+	case "esc", "ctrl-c":
+		v.prompt = ""
+	case "backspace":
+		if len(v.input) > 0 {
+			v.input = v.input[:len(v.input)-1]
+		}
+	case "enter":
+		v.submit(v.prompt, string(v.input))
+		v.prompt = ""
+	default:
+		if r, size := utf8.DecodeRuneInString(k); size == len(k) && r >= ' ' {
+			v.input = append(v.input, r)
+		}
+	}
+}
+
+// submit applies the text entered at a prompt
+func (v *viewer) submit(prompt, text string) {
+	var re *regexp.Regexp
+	if text != "" && prompt != "id: " {
+		var err error
+		if re, err = regexp.Compile(text); err != nil {
+			v.status = "bad pattern: " + err.Error()
+			return
+		}
+	}
+	switch prompt {
+	case "search: ":
+		v.search = re
+		if re != nil {
+			v.find(1)
+		}
+	case "grep: ":
+		v.grep = re
+		v.refilter()
+	case "id: ":
+		v.ids = splitList(strings.ToUpper(text))
+		v.refilter()
+	}
+}
+
+// move moves the selection by n messages. Moving away from the newest
+// message pauses follow mode.
+func (v *viewer) move(n int) {
+	v.cursor += n
+	if v.cursor >= len(v.shown) {
+		v.cursor = len(v.shown) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+	if n < 0 {
+		v.follow = false
+	}
+}
+
+// end selects the newest message and resumes follow mode
+func (v *viewer) end() {
+	v.cursor = len(v.shown) - 1
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+	v.follow = true
+	v.unseen = 0
+}
+
+// first selects the first occurrence of the selected message's ID
+func (v *viewer) first() {
+	if v.cursor >= len(v.shown) {
+		return
+	}
+	id := v.msgs[v.shown[v.cursor]].ID
+	count, at := 0, -1
+	for i, idx := range v.shown {
+		if v.msgs[idx].ID == id {
+			if at < 0 {
+				at = i
+			}
+			count++
+		}
+	}
+	v.cursor = at
+	v.follow = false
+	v.status = fmt.Sprintf("first of %d occurrences of %s", count, id)
+}
+
+// find selects the next (dir 1) or previous (dir -1) message matching the
+// search, wrapping around
+func (v *viewer) find(dir int) {
+	if v.search == nil {
+		v.status = "no search; press / to search"
+		return
+	}
+	n := len(v.shown)
+	if n == 0 {
+		return
+	}
+	for i := 1; i <= n; i++ {
+		at := ((v.cursor+dir*i)%n + n) % n
+		if v.search.MatchString(searchText(v.msgs[v.shown[at]])) {
+			v.cursor = at
+			v.follow = false
+			return
+		}
+	}
+	v.status = "pattern not found: " + v.search.String()
+}
+
+// cycleSeverity raises the minimum severity shown, then goes back to all
+func (v *viewer) cycleSeverity() {
+	min := 0
+	for sev := range v.severities {
+		if min == 0 || sev.Rank() < min {
+			min = sev.Rank()
+		}
+	}
+	v.severities = nil
+	for _, level := range severityLevels[1:] {
+		if level.Rank() > min {
+			v.severities = make(map[message.Severity]bool)
+			for _, sev := range severityLevels {
+				if sev.Rank() >= level.Rank() {
+					v.severities[sev] = true
+				}
+			}
+			break
+		}
+	}
+	v.refilter()
+}
+
+// listRows is the height of the message list
+func (v *viewer) listRows() int {
+	rows := v.rows - 2
+	if rows < 1 {
+		rows = 1
+	}
+	return rows
+}
+
+func (v *viewer) draw(w *bufio.Writer) {
+	rows := v.listRows()
+	var panel []string
+	if v.help && v.cursor < len(v.shown) {
+		panel = v.panel()
+		if max := rows / 2; len(panel) > max {
+			panel = panel[:max]
+		}
+		rows -= len(panel)
+	}
+
+	// Keep the selection on screen and the screen full
+	if v.top > len(v.shown)-rows {
+		v.top = len(v.shown) - rows
+	}
+	if v.top < 0 {
+		v.top = 0
+	}
+	if v.cursor < v.top {
+		v.top = v.cursor
+	}
+	if v.cursor >= v.top+rows {
+		v.top = v.cursor - rows + 1
+	}
+
+	w.WriteString("\x1b[H")
+	w.WriteString("\x1b[7m" + render.PadRight(truncate(v.title(), v.cols), v.cols) + "\x1b[0m\r\n")
+	for i := 0; i < rows; i++ {
+		if at := v.top + i; at < len(v.shown) {
+			w.WriteString(v.line(at))
+		}
+		w.WriteString("\x1b[K\r\n")
+	}
+	for _, line := range panel {
+		w.WriteString(line + "\x1b[K\r\n")
+	}
+
+	switch {
+	case v.prompt != "":
+		w.WriteString(truncate(v.prompt+string(v.input), v.cols-1) + "\x1b[K\x1b[?25h")
+		return
+	case v.status != "":
+		w.WriteString(truncate(v.status, v.cols))
+	default:
+		w.WriteString(v.style(v.theme.Timestamp) + truncate(viewKeys, v.cols) + v.reset())
+	}
+	w.WriteString("\x1b[K\x1b[?25l")
+}
+
+func (v *viewer) title() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, " %s  %d/%d messages  ", v.source, len(v.shown), len(v.msgs))
+	switch {
+	case v.follow:
+		b.WriteString("FOLLOW")
+	case v.unseen > 0:
+		fmt.Fprintf(&b, "PAUSED (+%d new)", v.unseen)
+	default:
+		b.WriteString("PAUSED")
+	}
+	if v.eof {
+		b.WriteString("  end of input")
+	}
+
+	var filters []string
+	if v.severities != nil {
+		var sevs []string
+		for _, sev := range severityLevels {
+			if v.severities[sev] {
+				sevs = append(sevs, string(sev))
+			}
+		}
+		filters = append(filters, "severity="+strings.Join(sevs, ","))
+	}
+	if len(v.ids) > 0 {
+		filters = append(filters, "id="+strings.Join(v.ids, ","))
+	}
+	if v.grep != nil {
+		filters = append(filters, "grep="+v.grep.String())
+	}
+	if len(filters) > 0 {
+		b.WriteString("  [" + strings.Join(filters, " ") + "]")
+	}
+	return b.String()
+}
+
+// line renders one row of the message list
+func (v *viewer) line(at int) string {
+	msg := v.msgs[v.shown[at]]
+	ts := strings.Repeat(" ", len(time.Stamp))
+	if !msg.Timestamp.IsZero() {
+		ts = msg.Timestamp.Format(time.Stamp)
+	}
+	id := fmt.Sprintf("%-8s", msg.ID)
+	sev := fmt.Sprintf("%-8s", msg.Severity)
+	text := strings.Join(strings.Fields(msg.Render()), " ")
+
+	prefix := ts + " " + id + " " + sev + " "
+	if at == v.cursor {
+		return "\x1b[7m" + render.PadRight(truncate(prefix+text, v.cols), v.cols) + "\x1b[0m"
+	}
+	room := v.cols - render.DisplayWidth(prefix)
+	if room < 0 {
+		return truncate(prefix, v.cols)
+	}
+	return v.style(v.theme.Timestamp) + ts + v.reset() + " " +
+		v.style(v.theme.ID) + id + v.reset() + " " +
+		v.style(v.theme.Severity(msg.Severity)) + sev + v.reset() + " " +
+		truncate(text, room)
+}
+
+// panel renders the selected message as a box with its catalog help
+func (v *viewer) panel() []string {
+	msg := v.msgs[v.shown[v.cursor]]
+	if entry, ok := v.cat.Lookup(msg.ID); ok {
+		if msg.Help == "" {
+			msg.Help = entry.Help
+		}
+		if len(msg.Replies) == 0 {
+			msg.Replies = entry.Replies
+		}
+		if len(msg.Links) == 0 {
+			msg.Links = entry.Links
+		}
+	}
+
+	width := v.cols
+	if width < 20 {
+		width = 20
+	}
+	r := render.Box{
+		Options: render.Options{
+			DisableColors: v.mode == render.ColorNone,
+			ColorMode:     v.mode,
+			Theme:         v.theme,
+			Hyperlinks:    v.links,
+		},
+		Width: width,
+	}
+	var buf bytes.Buffer
+	r.Render(&buf, msg)
+	return strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+}
+
+func (v *viewer) style(s render.Style) string {
+	return s.Sequence(v.mode)
+}
+
+func (v *viewer) reset() string {
+	if v.mode == render.ColorNone {
+		return ""
+	}
+	return "\x1b[0m"
+}
+
+// truncate cuts s to at most width columns
+func truncate(s string, width int) string {
+	if render.DisplayWidth(s) <= width {
+		return s
+	}
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := render.DisplayWidth(string(r))
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	if width > 0 {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func isCharDevice(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// tailReader reads a file like tail -f: at the end of the file it waits
+// for more to be written instead of returning io.EOF
+type tailReader struct {
+	f *os.File
+}
+
+func (t tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := t.f.Read(p)
+		if n > 0 || err != io.EOF {
+			return n, err
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// keySequences maps escape sequences to key names
+var keySequences = map[string]string{
+	"\x1b[A": "up", "\x1bOA": "up",
+	"\x1b[B": "down", "\x1bOB": "down",
+	"\x1b[5~": "pgup", "\x1b[6~": "pgdn",
+	"\x1b[H": "home", "\x1bOH": "home", "\x1b[1~": "home",
+	"\x1b[F": "end", "\x1bOF": "end", "\x1b[4~": "end",
+}
+
+// readKeys sends the keys typed on r to keys: a character, or a name such
+// as "up", "enter" or "esc". It closes keys when r fails.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		in := buf[:n]
+		for len(in) > 0 {
+			k, size := decodeKey(in)
+			in = in[size:]
+			if k != "" {
+				keys <- k
+			}
+		}
+	}
+}
+
+// decodeKey returns the first key in b and its length in bytes. Unknown
+// escape sequences are skipped.
+func decodeKey(b []byte) (string, int) {
+	switch b[0] {
+	case 0x1b:
+		if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+			return "esc", 1
+		}
+		// A sequence ends with a byte in @ to ~ after its parameters
+		end := 2
+		for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+			end++
+		}
+		if end < len(b) {
+			end++
+		}
+		return keySequences[string(b[:end])], end
+	case '\r', '\n':
+		return "enter", 1
+	case 0x7f, 0x08:
+		return "backspace", 1
+	case 0x03:
+		return "ctrl-c", 1
+	}
+	r, size := utf8.DecodeRune(b)
+	return string(r), size
+}
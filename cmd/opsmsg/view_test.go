@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestViewerKeep(t *testing.T) {
+	v := &viewer{follow: true, keep: 5, ids: []string{"SRV"}}
+	for i := 0; i < 12; i++ {
+		id := "SRV"
+		if i%2 == 1 {
+			id = "DB"
+		}
+		v.add(message.Message{ID: id + strconv.Itoa(i)})
+	}
+	if len(v.msgs) != 5 || v.msgs[0].ID != "DB7" || v.msgs[4].ID != "DB11" {
+		t.Fatalf("kept %d messages from %s", len(v.msgs), v.msgs[0].ID)
+	}
+	var shown []string
+	for _, i := range v.shown {
+		shown = append(shown, v.msgs[i].ID)
+	}
+	if len(shown) != 2 || shown[0] != "SRV8" || shown[1] != "SRV10" {
+		t.Errorf("shown = %v, want [SRV8 SRV10]", shown)
+	}
+	if v.cursor != 1 {
+		t.Errorf("cursor = %d, want the newest", v.cursor)
+	}
+
+	// A paused selection moves with its message until that is dropped
+	v.follow = false
+	v.cursor = 0
+	v.add(message.Message{ID: "SRV12"})
+	if got := v.msgs[v.shown[v.cursor]].ID; got != "SRV8" {
+		t.Errorf("selected %s, want SRV8", got)
+	}
+	v.add(message.Message{ID: "DB13"})
+	v.add(message.Message{ID: "SRV14"})
+	if got := v.msgs[v.shown[v.cursor]].ID; got != "SRV10" {
+		t.Errorf("selected %s after SRV8 was dropped, want SRV10", got)
+	}
+	if v.unseen > len(v.shown) {
+		t.Errorf("unseen = %d with %d shown", v.unseen, len(v.shown))
+	}
+}
@@ -0,0 +1,128 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// AlertmanagerDispatcher posts messages as alerts to the Prometheus
+// Alertmanager v2 API
+type AlertmanagerDispatcher struct {
+	// URL is the Alertmanager base URL, e.g. http://localhost:9093
+	URL string
+	// Client is the HTTP client used for requests (default: http.DefaultClient)
+	Client *http.Client
+	// GeneratorURL is attached to every alert, e.g. a runbook or dashboard
+	GeneratorURL string
+	// Labels are added to every alert
+	Labels map[string]string
+	// LabelFields restricts which context keys become labels. All context
+	// keys are used when empty; the remaining keys become annotations.
+	LabelFields []string
+	// MinSeverity is the lowest severity that is sent as an alert
+	MinSeverity message.Severity
+}
+
+// NewAlertmanagerDispatcher creates a dispatcher that sends WARN and more
+// severe messages to the Alertmanager at url
+func NewAlertmanagerDispatcher(url string) *AlertmanagerDispatcher {
+	return &AlertmanagerDispatcher{
+		URL:         url,
+		MinSeverity: message.Warn,
+	}
+}
+
+type alertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     string            `json:"startsAt,omitempty"`
+	EndsAt       string            `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// Dispatch fires an alert for messages at or above MinSeverity.
+// Less severe messages are ignored.
+func (d *AlertmanagerDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	if msg.Severity.Rank() < d.MinSeverity.Rank() {
+		return nil
+	}
+	return d.send(ctx, d.alert(msg, time.Time{}))
+}
+
+// Resolve marks the alert for msg as resolved
+func (d *AlertmanagerDispatcher) Resolve(ctx context.Context, msg message.Message) error {
+	return d.send(ctx, d.alert(msg, time.Now()))
+}
+
+func (d *AlertmanagerDispatcher) alert(msg message.Message, endsAt time.Time) alertmanagerAlert {
+	labels := make(map[string]string, len(d.Labels)+len(msg.Context)+2)
+	annotations := map[string]string{
+		"summary": msg.Render(),
+	}
+	if msg.Help != "" {
+		annotations["description"] = msg.Help
+	}
+
+	for k, v := range d.Labels {
+		labels[k] = v
+	}
+	for k, v := range msg.Context {
+		if d.isLabel(k) {
+			labels[labelName(k)] = v
+		} else {
+			annotations[k] = v
+		}
+	}
+	labels["alertname"] = msg.ID
+	labels["severity"] = strings.ToLower(string(msg.Severity))
+
+	a := alertmanagerAlert{
+		Labels:       labels,
+		Annotations:  annotations,
+		GeneratorURL: d.GeneratorURL,
+	}
+	if !msg.Timestamp.IsZero() {
+		a.StartsAt = msg.Timestamp.UTC().Format(time.RFC3339)
+	}
+	if !endsAt.IsZero() {
+		a.EndsAt = endsAt.UTC().Format(time.RFC3339)
+	}
+	return a
+}
+
+func (d *AlertmanagerDispatcher) isLabel(key string) bool {
+	if len(d.LabelFields) == 0 {
+		return true
+	}
+	for _, f := range d.LabelFields {
+		if f == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *AlertmanagerDispatcher) send(ctx context.Context, alert alertmanagerAlert) error {
+	url := strings.TrimSuffix(d.URL, "/") + "/api/v2/alerts"
+	return postJSON(ctx, d.Client, url, nil, []alertmanagerAlert{alert})
+}
+
+// labelName maps a context key to a valid Prometheus label name
+func labelName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	return b.String()
+}
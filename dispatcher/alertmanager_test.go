@@ -0,0 +1,152 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func alertmanagerMessage() message.Message {
+	return message.Message{
+		ID:        "DEP005",
+		Severity:  message.Error,
+		Text:      "Deployment {name} failed",
+		Context:   map[string]string{"name": "api", "k8s.namespace": "prod"},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Help:      "Cause: image pull failed.",
+	}
+}
+
+func TestAlertmanagerDispatch(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewAlertmanagerDispatcher(rec.URL + "/")
+	d.GeneratorURL = "https://runbooks.example.com/DEP005"
+	d.Labels = map[string]string{"team": "platform"}
+
+	if err := d.Dispatch(context.Background(), alertmanagerMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if got := rec.received()[0].Path; got != "/api/v2/alerts" {
+		t.Errorf("path = %q", got)
+	}
+	var alerts []alertmanagerAlert
+	rec.decode(t, 0, &alerts)
+	if len(alerts) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerts))
+	}
+	a := alerts[0]
+
+	wantLabels := map[string]string{
+		"alertname":     "DEP005",
+		"severity":      "error",
+		"team":          "platform",
+		"name":          "api",
+		"k8s_namespace": "prod",
+	}
+	if len(a.Labels) != len(wantLabels) {
+		t.Errorf("labels = %v, want %v", a.Labels, wantLabels)
+	}
+	for k, v := range wantLabels {
+		if a.Labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, a.Labels[k], v)
+		}
+	}
+	if a.Annotations["summary"] != "Deployment api failed" || a.Annotations["description"] != "Cause: image pull failed." {
+		t.Errorf("annotations = %v", a.Annotations)
+	}
+	if a.StartsAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("startsAt = %q", a.StartsAt)
+	}
+	if a.EndsAt != "" {
+		t.Errorf("firing alert has endsAt %q", a.EndsAt)
+	}
+	if a.GeneratorURL != d.GeneratorURL {
+		t.Errorf("generatorURL = %q", a.GeneratorURL)
+	}
+}
+
+func TestAlertmanagerLabelFields(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewAlertmanagerDispatcher(rec.URL)
+	d.LabelFields = []string{"k8s.namespace"}
+
+	if err := d.Dispatch(context.Background(), alertmanagerMessage()); err != nil {
+		t.Fatal(err)
+	}
+	var alerts []alertmanagerAlert
+	rec.decode(t, 0, &alerts)
+	a := alerts[0]
+	if a.Labels["k8s_namespace"] != "prod" {
+		t.Errorf("labels = %v, want k8s_namespace", a.Labels)
+	}
+	if _, ok := a.Labels["name"]; ok {
+		t.Error("context key outside LabelFields became a label")
+	}
+	if a.Annotations["name"] != "api" {
+		t.Errorf("annotations = %v, want name", a.Annotations)
+	}
+}
+
+func TestAlertmanagerResolve(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewAlertmanagerDispatcher(rec.URL)
+
+	before := time.Now().UTC().Truncate(time.Second)
+	if err := d.Resolve(context.Background(), alertmanagerMessage()); err != nil {
+		t.Fatal(err)
+	}
+	var alerts []alertmanagerAlert
+	rec.decode(t, 0, &alerts)
+	endsAt, err := time.Parse(time.RFC3339, alerts[0].EndsAt)
+	if err != nil {
+		t.Fatalf("endsAt %q: %v", alerts[0].EndsAt, err)
+	}
+	if endsAt.Before(before) || endsAt.After(time.Now().Add(time.Second)) {
+		t.Errorf("endsAt = %v, want about now", endsAt)
+	}
+	if alerts[0].Labels["alertname"] != "DEP005" {
+		t.Errorf("resolve labels = %v; they must match the firing alert", alerts[0].Labels)
+	}
+}
+
+func TestAlertmanagerMinSeverity(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewAlertmanagerDispatcher(rec.URL)
+	msg := alertmanagerMessage()
+	msg.Severity = message.Info
+
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.received()); n != 0 {
+		t.Errorf("INFO message sent %d requests", n)
+	}
+}
+
+func TestAlertmanagerErrorStatus(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusInternalServerError
+	d := NewAlertmanagerDispatcher(rec.URL)
+
+	err := d.Dispatch(context.Background(), alertmanagerMessage())
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Dispatch error = %v, want the 500 status", err)
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	for in, want := range map[string]string{
+		"port":          "port",
+		"k8s.namespace": "k8s_namespace",
+		"1st":           "_st",
+		"a-b c":         "a_b_c",
+	} {
+		if got := labelName(in); got != want {
+			t.Errorf("labelName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
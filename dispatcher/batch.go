@@ -0,0 +1,98 @@
+package dispatcher
+
+import (
+	"sync"
+	"time"
+)
+
+// batcher queues items for dispatchers that send in batches. A timer
+// started with the first item calls a flush function every interval
+// until close.
+type batcher[T any] struct {
+	mu      sync.Mutex
+	pending []T
+	closed  bool
+	start   sync.Once
+	closing sync.Once
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// add queues item and returns the queue length. The first call starts
+// the timer calling tick every interval. add returns false without
+// queueing once the batcher is closed.
+func (b *batcher[T]) add(item T, interval time.Duration, tick func()) (int, bool) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, false
+	}
+	b.pending = append(b.pending, item)
+	n := len(b.pending)
+	b.mu.Unlock()
+
+	b.start.Do(func() { b.run(interval, tick) })
+	return n, true
+}
+
+// take removes and returns the queued items
+func (b *batcher[T]) take() []T {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	items := b.pending
+	b.pending = nil
+	return items
+}
+
+// requeue puts items that failed to send back in front of the queue,
+// keeping at most limit items; the oldest are dropped first. It returns
+// the number of items dropped.
+func (b *batcher[T]) requeue(items []T, limit int) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := append(items, b.pending...)
+	dropped := 0
+	if limit > 0 && len(queue) > limit {
+		dropped = len(queue) - limit
+		queue = queue[dropped:]
+	}
+	b.pending = queue
+	return dropped
+}
+
+// close stops the timer; items added afterwards are refused. It reports
+// false when the batcher was already closed.
+func (b *batcher[T]) close() bool {
+	first := false
+	b.closing.Do(func() {
+		first = true
+		b.mu.Lock()
+		b.closed = true
+		b.mu.Unlock()
+
+		b.start.Do(func() {})
+		if b.stop != nil {
+			close(b.stop)
+			<-b.done
+		}
+	})
+	return first
+}
+
+func (b *batcher[T]) run(interval time.Duration, tick func()) {
+	b.stop = make(chan struct{})
+	b.done = make(chan struct{})
+	go func() {
+		defer close(b.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				tick()
+			case <-b.stop:
+				return
+			}
+		}
+	}()
+}
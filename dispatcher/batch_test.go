@@ -0,0 +1,26 @@
+package dispatcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBatcherRequeue(t *testing.T) {
+	var b batcher[int]
+	b.add(3, time.Hour, func() {})
+	if dropped := b.requeue([]int{1, 2}, 2); dropped != 1 {
+		t.Errorf("dropped %d, want 1", dropped)
+	}
+	if got := b.take(); len(got) != 2 || got[0] != 2 || got[1] != 3 {
+		t.Errorf("queue = %v, want [2 3]", got)
+	}
+	if !b.close() || b.close() {
+		t.Error("close should report true once")
+	}
+	if _, ok := b.add(4, time.Hour, func() {}); ok {
+		t.Error("add succeeded after close")
+	}
+	if len(b.take()) != 0 {
+		t.Error("closed batcher queued an item")
+	}
+}
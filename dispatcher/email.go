@@ -0,0 +1,356 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// EmailDispatcher sends messages as email over SMTP, either one mail per
+// message or as a periodic digest
+type EmailDispatcher struct {
+	// Addr is the SMTP server address (host:port)
+	Addr string
+	// From is the sender address
+	From string
+	// To lists the recipient addresses
+	To []string
+	// Username and Password enable AUTH PLAIN when Username is set
+	Username string
+	Password string
+	// TLSConfig is used for STARTTLS (default: ServerName from Addr)
+	TLSConfig *tls.Config
+	// RequireTLS fails delivery when the server does not offer STARTTLS
+	RequireTLS bool
+	// SubjectPrefix is prepended to every subject (default: "[opsmsg]")
+	SubjectPrefix string
+	// MinSeverity is the lowest severity that is mailed
+	MinSeverity message.Severity
+	// DigestInterval batches messages into one mail per interval.
+	// Every message is mailed immediately when zero.
+	DigestInterval time.Duration
+	// OnError receives delivery errors of periodic digests
+	OnError func(error)
+
+	batch batcher[message.Message]
+}
+
+// NewEmailDispatcher creates a dispatcher that mails every message
+// immediately
+func NewEmailDispatcher(addr, from string, to ...string) *EmailDispatcher {
+	return &EmailDispatcher{
+		Addr: addr,
+		From: from,
+		To:   to,
+	}
+}
+
+// Dispatch mails msg, or queues it for the next digest when
+// DigestInterval is set. After Close, messages are mailed immediately.
+func (d *EmailDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	if msg.Severity.Rank() < d.MinSeverity.Rank() {
+		return nil
+	}
+	if d.DigestInterval <= 0 {
+		return d.send(ctx, []message.Message{msg})
+	}
+
+	if _, ok := d.batch.add(msg, d.DigestInterval, d.tick); !ok {
+		return d.send(ctx, []message.Message{msg})
+	}
+	return nil
+}
+
+// Flush sends all queued messages as one digest
+func (d *EmailDispatcher) Flush(ctx context.Context) error {
+	msgs := d.batch.take()
+	if len(msgs) == 0 {
+		return nil
+	}
+	return d.send(ctx, msgs)
+}
+
+// Close stops the digest timer and sends any queued messages. Calling
+// it again does nothing.
+func (d *EmailDispatcher) Close() error {
+	if !d.batch.close() {
+		return nil
+	}
+	return d.Flush(context.Background())
+}
+
+// tick sends the periodic digest
+func (d *EmailDispatcher) tick() {
+	if err := d.Flush(context.Background()); err != nil && d.OnError != nil {
+		d.OnError(err)
+	}
+}
+
+func (d *EmailDispatcher) send(ctx context.Context, msgs []message.Message) error {
+	body, err := d.compose(msgs)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	host, _, _ := net.SplitHostPort(d.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		config := d.TLSConfig
+		if config == nil {
+			config = &tls.Config{ServerName: host}
+		}
+		if err := c.StartTLS(config); err != nil {
+			return err
+		}
+	} else if d.RequireTLS {
+		return fmt.Errorf("smtp %s: server does not support STARTTLS", d.Addr)
+	}
+
+	if d.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", d.Username, d.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(d.From); err != nil {
+		return err
+	}
+	for _, to := range d.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// compose renders msgs into a multipart/alternative mail with plain-text
+// and HTML bodies
+func (d *EmailDispatcher) compose(msgs []message.Message) ([]byte, error) {
+	data := emailData{Digest: len(msgs) > 1 || d.DigestInterval > 0, Count: len(msgs)}
+	data.Groups = groupMessages(msgs)
+
+	var text, html bytes.Buffer
+	if err := emailTextTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := emailHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("From", d.From)
+	header.Set("To", strings.Join(d.To, ", "))
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", d.subject(data, msgs)))
+	header.Set("Date", time.Now().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(d.From))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, header.Get(k))
+	}
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{"text/plain; charset=utf-8", text.Bytes()},
+		{"text/html; charset=utf-8", html.Bytes()},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		qp.Write(part.body)
+		qp.Close()
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (d *EmailDispatcher) subject(data emailData, msgs []message.Message) string {
+	prefix := d.SubjectPrefix
+	if prefix == "" {
+		prefix = "[opsmsg]"
+	}
+	if !data.Digest {
+		msg := msgs[0]
+		return fmt.Sprintf("%s %s %s: %s", prefix, msg.Severity, msg.ID, msg.Render())
+	}
+
+	var counts []string
+	for _, g := range data.Groups {
+		n := 0
+		for _, id := range g.IDs {
+			n += len(id.Messages)
+		}
+		counts = append(counts, fmt.Sprintf("%d %s", n, g.Severity))
+	}
+	return fmt.Sprintf("%s digest: %d messages (%s)", prefix, len(msgs), strings.Join(counts, ", "))
+}
+
+type emailData struct {
+	Digest bool
+	Count  int
+	Groups []emailSeverityGroup
+}
+
+type emailSeverityGroup struct {
+	Severity message.Severity
+	IDs      []emailIDGroup
+}
+
+type emailIDGroup struct {
+	ID       string
+	Text     string
+	Help     string
+	Messages []message.Message
+}
+
+// groupMessages groups msgs by severity, most severe first, then by ID
+func groupMessages(msgs []message.Message) []emailSeverityGroup {
+	bySeverity := make(map[message.Severity]map[string]*emailIDGroup)
+	for _, msg := range msgs {
+		ids := bySeverity[msg.Severity]
+		if ids == nil {
+			ids = make(map[string]*emailIDGroup)
+			bySeverity[msg.Severity] = ids
+		}
+		g := ids[msg.ID]
+		if g == nil {
+			g = &emailIDGroup{ID: msg.ID, Text: msg.Text, Help: msg.Help}
+			ids[msg.ID] = g
+		}
+		g.Messages = append(g.Messages, msg)
+	}
+
+	var groups []emailSeverityGroup
+	for sev, ids := range bySeverity {
+		group := emailSeverityGroup{Severity: sev}
+		for _, g := range ids {
+			group.IDs = append(group.IDs, *g)
+		}
+		sort.Slice(group.IDs, func(i, j int) bool { return group.IDs[i].ID < group.IDs[j].ID })
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		ri, rj := groups[i].Severity.Rank(), groups[j].Severity.Rank()
+		if ri != rj {
+			return ri > rj
+		}
+		return groups[i].Severity < groups[j].Severity
+	})
+	return groups
+}
+
+type emailField struct {
+	Key   string
+	Value string
+}
+
+func sortedContext(ctx map[string]string) []emailField {
+	fields := make([]emailField, 0, len(ctx))
+	for k, v := range ctx {
+		fields = append(fields, emailField{k, v})
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	return fields
+}
+
+func messageID(from string) string {
+	domain := "opsmsg"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+var emailFuncs = map[string]interface{}{
+	"fields":    sortedContext,
+	"timestamp": func(t time.Time) string { return t.Format(time.RFC3339) },
+	"lower":     func(s message.Severity) string { return strings.ToLower(string(s)) },
+}
+
+var emailTextTemplate = template.Must(template.New("text").Funcs(emailFuncs).Parse(
+	`{{if .Digest}}opsmsg digest: {{.Count}} messages
+{{end}}{{range .Groups}}{{if $.Digest}}
+== {{.Severity}} ==
+{{end}}{{range .IDs}}
+{{.ID}} ({{(index .Messages 0).Severity}}){{if $.Digest}} x{{len .Messages}}{{end}}
+{{range .Messages}}  [{{timestamp .Timestamp}}] {{.Render}}
+{{range fields .Context}}      {{.Key}}={{.Value}}
+{{end}}{{end}}{{if .Help}}  Help: {{.Help}}
+{{end}}{{end}}{{end}}`))
+
+var emailHTMLTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(emailFuncs).Parse(
+	`<!DOCTYPE html>
+<html><body style="font-family: sans-serif">
+{{if .Digest}}<h2>opsmsg digest: {{.Count}} messages</h2>
+{{end}}{{range .Groups}}{{if $.Digest}}<h3 class="severity-{{lower .Severity}}">{{.Severity}}</h3>
+{{end}}{{range .IDs}}<div class="message severity-{{lower (index .Messages 0).Severity}}">
+<p><strong>{{.ID}}</strong> ({{(index .Messages 0).Severity}}){{if $.Digest}} &times;{{len .Messages}}{{end}}</p>
+<ul>
+{{range .Messages}}<li><code>{{timestamp .Timestamp}}</code> {{.Render}}{{with fields .Context}}
+<table>{{range .}}<tr><td>{{.Key}}</td><td>{{.Value}}</td></tr>{{end}}</table>{{end}}</li>
+{{end}}</ul>
+{{if .Help}}<p><em>Help:</em> {{.Help}}</p>
+{{end}}</div>
+{{end}}{{end}}</body></html>
+`))
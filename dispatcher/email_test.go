@@ -0,0 +1,300 @@
+package dispatcher
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// smtpMail is a mail received by the SMTP stand-in
+type smtpMail struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpServer is an in-process SMTP stand-in. It offers no extensions and
+// rejects recipients listed in Reject.
+type smtpServer struct {
+	Addr   string
+	Reject map[string]bool
+
+	ln    net.Listener
+	mu    sync.Mutex
+	mails []smtpMail
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{Addr: ln.Addr().String(), ln: ln}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	var m smtpMail
+	reply("220 localhost stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			reply("250 localhost")
+		case "MAIL":
+			m = smtpMail{From: addrArg(cmd)}
+			reply("250 OK")
+		case "RCPT":
+			to := addrArg(cmd)
+			if s.Reject[to] {
+				reply("550 no such user")
+				continue
+			}
+			m.To = append(m.To, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data bytes.Buffer
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			m.Data = data.Bytes()
+			s.mu.Lock()
+			s.mails = append(s.mails, m)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+// addrArg returns the address in "MAIL FROM:<a@b>"
+func addrArg(cmd string) string {
+	i, j := strings.IndexByte(cmd, '<'), strings.IndexByte(cmd, '>')
+	if i < 0 || j < i {
+		return ""
+	}
+	return cmd[i+1 : j]
+}
+
+func (s *smtpServer) received() []smtpMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMail(nil), s.mails...)
+}
+
+// parsedMail is a received mail split into its headers and bodies
+type parsedMail struct {
+	Header mail.Header
+	Text   string
+	HTML   string
+}
+
+func parseMail(t *testing.T, data []byte) parsedMail {
+	t.Helper()
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	_, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := parsedMail{Header: m.Header}
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The part decodes its quoted-printable body itself
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			p.HTML = string(body)
+		} else {
+			p.Text = string(body)
+		}
+	}
+	return p
+}
+
+func emailMessage(id string, sev message.Severity, port string) message.Message {
+	return message.Message{
+		ID:        id,
+		Severity:  sev,
+		Text:      "Failed to bind to port {port}",
+		Context:   map[string]string{"port": port},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Help:      "Cause: port in use.",
+	}
+}
+
+func TestEmailImmediate(t *testing.T) {
+	srv := newSMTPServer(t)
+	d := NewEmailDispatcher(srv.Addr, "ops@example.com", "oncall@example.com", "team@example.com")
+
+	if err := d.Dispatch(context.Background(), emailMessage("SRV002", message.Error, "8080")); err != nil {
+		t.Fatal(err)
+	}
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want 1", len(mails))
+	}
+	if mails[0].From != "ops@example.com" || strings.Join(mails[0].To, ",") != "oncall@example.com,team@example.com" {
+		t.Errorf("envelope = %s -> %v", mails[0].From, mails[0].To)
+	}
+
+	m := parseMail(t, mails[0].Data)
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[opsmsg] ERROR SRV002: Failed to bind to port 8080"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	if !strings.Contains(m.Text, "Failed to bind to port 8080") || !strings.Contains(m.Text, "port=8080") || !strings.Contains(m.Text, "Help: Cause: port in use.") {
+		t.Errorf("text body:\n%s", m.Text)
+	}
+	if !strings.Contains(m.HTML, "<strong>SRV002</strong>") {
+		t.Errorf("HTML body:\n%s", m.HTML)
+	}
+}
+
+func TestEmailDigest(t *testing.T) {
+	srv := newSMTPServer(t)
+	d := NewEmailDispatcher(srv.Addr, "ops@example.com", "oncall@example.com")
+	d.DigestInterval = time.Hour
+	d.MinSeverity = message.Warn
+
+	ctx := context.Background()
+	for _, msg := range []message.Message{
+		emailMessage("SRV002", message.Error, "8080"),
+		emailMessage("SRV002", message.Error, "9090"),
+		emailMessage("SRV003", message.Critical, "1"),
+		emailMessage("SRV001", message.Info, "1"), // below MinSeverity
+	} {
+		if err := d.Dispatch(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Fatalf("%d mails sent before the digest", n)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mails := srv.received()
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want one digest", len(mails))
+	}
+	m := parseMail(t, mails[0].Data)
+	subject, _ := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if want := "[opsmsg] digest: 3 messages (1 CRITICAL, 2 ERROR)"; subject != want {
+		t.Errorf("subject = %q, want %q", subject, want)
+	}
+	critical, errors := strings.Index(m.Text, "== CRITICAL =="), strings.Index(m.Text, "== ERROR ==")
+	if critical < 0 || errors < critical {
+		t.Errorf("digest is not grouped most severe first:\n%s", m.Text)
+	}
+	if !strings.Contains(m.Text, "SRV002 (ERROR) x2") {
+		t.Errorf("digest does not count repeated IDs:\n%s", m.Text)
+	}
+}
+
+func TestEmailCloseTwice(t *testing.T) {
+	srv := newSMTPServer(t)
+	d := NewEmailDispatcher(srv.Addr, "ops@example.com", "oncall@example.com")
+	d.DigestInterval = time.Hour
+
+	if err := d.Dispatch(context.Background(), emailMessage("SRV002", message.Error, "8080")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	// Nothing would flush a batch any more, so this is mailed at once
+	if err := d.Dispatch(context.Background(), emailMessage("SRV003", message.Critical, "1")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(srv.received()); n != 2 {
+		t.Errorf("got %d mails, want the digest and the message after Close", n)
+	}
+}
+
+func TestEmailRejectedRecipient(t *testing.T) {
+	srv := newSMTPServer(t)
+	srv.Reject = map[string]bool{"gone@example.com": true}
+	d := NewEmailDispatcher(srv.Addr, "ops@example.com", "gone@example.com")
+
+	err := d.Dispatch(context.Background(), emailMessage("SRV002", message.Error, "8080"))
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("Dispatch error = %v, want the 550 reply", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("%d mails delivered", n)
+	}
+}
+
+func TestEmailRequireTLS(t *testing.T) {
+	srv := newSMTPServer(t)
+	d := NewEmailDispatcher(srv.Addr, "ops@example.com", "oncall@example.com")
+	d.RequireTLS = true
+
+	err := d.Dispatch(context.Background(), emailMessage("SRV002", message.Error, "8080"))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Dispatch error = %v, want a STARTTLS error", err)
+	}
+}
@@ -0,0 +1,44 @@
+package dispatcher
+
+import (
+	"github.com/martencassel/opsmsg/encode"
+	"github.com/sirupsen/logrus"
+)
+
+// ECSFormatter formats log entries as Elastic Common Schema JSON lines
+type ECSFormatter struct {
+	encode.ECS
+}
+
+// Format renders an Entry as one ECS document per line
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return encodeEntry(f.ECS, entry)
+}
+
+// GELFFormatter formats log entries as GELF 1.1 JSON lines
+type GELFFormatter struct {
+	encode.GELF
+}
+
+// Format renders an Entry as one GELF document per line
+func (f *GELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return encodeEntry(f.GELF, entry)
+}
+
+// LogfmtFormatter formats log entries as logfmt lines
+type LogfmtFormatter struct {
+	encode.Logfmt
+}
+
+// Format renders an Entry as one logfmt line
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	return encodeEntry(f.Logfmt, entry)
+}
+
+func encodeEntry(e encode.Encoder, entry *logrus.Entry) ([]byte, error) {
+	data, err := e.Encode(entryMessage(entry))
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
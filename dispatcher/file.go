@@ -0,0 +1,323 @@
+package dispatcher
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+	"github.com/sirupsen/logrus"
+)
+
+// backupTimeFormat is embedded in rotated file names
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// FileDispatcher writes formatted messages to a file, rotating it by size
+// and age. It also implements io.Writer so it can be used as a logrus
+// output.
+type FileDispatcher struct {
+	// Path is the file messages are written to
+	Path string
+	// Formatter renders messages (default: SimpleIBMFormatter without colors)
+	Formatter logrus.Formatter
+	// MaxSize rotates the file before it grows beyond this many bytes
+	MaxSize int64
+	// MaxAge rotates the file once it has been open this long
+	MaxAge time.Duration
+	// MaxBackups is the number of rotated files to keep (0 keeps all)
+	MaxBackups int
+	// MaxBackupAge removes rotated files older than this (0 keeps all)
+	MaxBackupAge time.Duration
+	// Compress gzips rotated files
+	Compress bool
+	// OnError receives errors of reopening on a signal and of compressing
+	// rotated files in the background
+	OnError func(error)
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+	wg     sync.WaitGroup
+	// now is the clock, replaced in tests
+	now func() time.Time
+}
+
+// NewFileDispatcher creates a dispatcher writing to path. The file is
+// opened on first write.
+func NewFileDispatcher(path string) *FileDispatcher {
+	return &FileDispatcher{Path: path}
+}
+
+// Dispatch formats msg and appends it to the file
+func (d *FileDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	formatter := d.Formatter
+	if formatter == nil {
+		formatter = &SimpleIBMFormatter{DisableColors: true}
+	}
+	// The formatter sees the file as its output, so ColorAuto leaves out
+	// escape codes
+	entry := messageEntry(msg)
+	entry.Logger = &logrus.Logger{Out: d}
+	data, err := formatter.Format(entry)
+	if err != nil {
+		return err
+	}
+	_, err = d.Write(data)
+	return err
+}
+
+// Write appends p to the file, rotating first if p would exceed MaxSize or
+// the file is older than MaxAge
+func (d *FileDispatcher) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file == nil {
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	tooBig := d.MaxSize > 0 && d.size > 0 && d.size+int64(len(p)) > d.MaxSize
+	tooOld := d.MaxAge > 0 && d.clock().Sub(d.opened) >= d.MaxAge
+	if tooBig || tooOld {
+		if err := d.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := d.file.Write(p)
+	d.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one
+func (d *FileDispatcher) Rotate() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.rotate()
+}
+
+// Reopen closes and reopens the file at Path, e.g. after an external tool
+// such as logrotate has moved it
+func (d *FileDispatcher) Reopen() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.file != nil {
+		if err := d.file.Close(); err != nil {
+			return err
+		}
+		d.file = nil
+	}
+	return d.open()
+}
+
+// ReopenOnSignal reopens the file whenever the process receives one of
+// sigs (default: SIGHUP). Call the returned function to stop listening.
+func (d *FileDispatcher) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := d.Reopen(); err != nil && d.OnError != nil {
+					d.OnError(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// Close closes the file and waits for pending compression to finish
+func (d *FileDispatcher) Close() error {
+	d.mu.Lock()
+	var err error
+	if d.file != nil {
+		err = d.file.Close()
+		d.file = nil
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+	return err
+}
+
+func (d *FileDispatcher) open() error {
+	if err := os.MkdirAll(filepath.Dir(d.Path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(d.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	d.file = f
+	d.size = info.Size()
+	d.opened = d.started(info)
+	return nil
+}
+
+// started returns when the file at Path was started, so that MaxAge
+// counts across restarts: when it was last rotated, or its modification
+// time when there is no backup since
+func (d *FileDispatcher) started(info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return d.clock()
+	}
+	if b := d.backups(); len(b) > 0 && !b[0].time.After(info.ModTime()) {
+		return b[0].time
+	}
+	return info.ModTime()
+}
+
+func (d *FileDispatcher) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+func (d *FileDispatcher) rotate() error {
+	if d.file != nil {
+		if err := d.file.Close(); err != nil {
+			return err
+		}
+		d.file = nil
+	}
+
+	// Never overwrite an earlier backup from the same millisecond
+	now := d.clock()
+	backup := d.backupName(now)
+	for fileExists(backup) || fileExists(backup+".gz") {
+		now = now.Add(time.Millisecond)
+		backup = d.backupName(now)
+	}
+	if err := os.Rename(d.Path, backup); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := d.open(); err != nil {
+		return err
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		if d.Compress {
+			if err := compressFile(backup); err != nil && d.OnError != nil {
+				d.OnError(err)
+			}
+		}
+		d.removeOldBackups()
+	}()
+	return nil
+}
+
+// backupName returns e.g. "app-2006-01-02T15-04-05.000.log" for "app.log"
+func (d *FileDispatcher) backupName(t time.Time) string {
+	ext := filepath.Ext(d.Path)
+	base := strings.TrimSuffix(d.Path, ext)
+	return base + "-" + t.Format(backupTimeFormat) + ext
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+func (d *FileDispatcher) backups() []backupFile {
+	ext := filepath.Ext(d.Path)
+	prefix := filepath.Base(strings.TrimSuffix(d.Path, ext)) + "-"
+
+	entries, err := os.ReadDir(filepath.Dir(d.Path))
+	if err != nil {
+		return nil
+	}
+	var files []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(name, prefix)
+		stamp = strings.TrimSuffix(stamp, ".gz")
+		stamp = strings.TrimSuffix(stamp, ext)
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		files = append(files, backupFile{filepath.Join(filepath.Dir(d.Path), name), t})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].time.After(files[j].time) })
+	return files
+}
+
+func (d *FileDispatcher) removeOldBackups() {
+	if d.MaxBackups <= 0 && d.MaxBackupAge <= 0 {
+		return
+	}
+	cutoff := d.clock().Add(-d.MaxBackupAge)
+	for i, b := range d.backups() {
+		if (d.MaxBackups > 0 && i >= d.MaxBackups) || (d.MaxBackupAge > 0 && b.time.Before(cutoff)) {
+			os.Remove(b.path)
+		}
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// compressFile replaces path with path.gz
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
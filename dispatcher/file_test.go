@@ -0,0 +1,298 @@
+package dispatcher
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// fakeClock is a settable clock for FileDispatcher.now
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+// newFile returns a dispatcher writing app.log in a temporary directory
+// on a fake clock
+func newFile(t *testing.T) (*FileDispatcher, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2024, 5, 1, 12, 0, 0, 0, time.Local)}
+	d := NewFileDispatcher(filepath.Join(t.TempDir(), "logs", "app.log"))
+	d.now = clock.now
+	t.Cleanup(func() { d.Close() })
+	return d, clock
+}
+
+// backupNames lists the rotated files next to d.Path, oldest first
+func backupNames(t *testing.T, d *FileDispatcher) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Dir(d.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		if e.Name() != filepath.Base(d.Path) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func write(t *testing.T, d *FileDispatcher, s string) {
+	t.Helper()
+	if _, err := io.WriteString(d, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileRotateBySize(t *testing.T) {
+	d, clock := newFile(t)
+	d.MaxSize = 20
+
+	write(t, d, "line one\n") // 9 bytes
+	write(t, d, "line two\n") // 18 bytes
+	clock.advance(time.Second)
+	write(t, d, "line three\n") // 29 bytes would exceed MaxSize: rotates first
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backups := backupNames(t, d)
+	if len(backups) != 1 || backups[0] != "app-2024-05-01T12-00-01.000.log" {
+		t.Fatalf("backups = %v", backups)
+	}
+	if got := readFile(t, filepath.Join(filepath.Dir(d.Path), backups[0])); got != "line one\nline two\n" {
+		t.Errorf("backup = %q", got)
+	}
+	if got := readFile(t, d.Path); got != "line three\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestFileRotateByAge(t *testing.T) {
+	d, clock := newFile(t)
+	d.MaxAge = time.Hour
+
+	write(t, d, "first\n")
+	clock.advance(59 * time.Minute)
+	write(t, d, "second\n")
+	if n := len(backupNames(t, d)); n != 0 {
+		t.Fatalf("%d backups before MaxAge", n)
+	}
+	clock.advance(time.Minute)
+	write(t, d, "third\n")
+	d.Close()
+
+	if backups := backupNames(t, d); len(backups) != 1 {
+		t.Fatalf("backups = %v", backups)
+	}
+	if got := readFile(t, d.Path); got != "third\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestFileAgeSurvivesRestart(t *testing.T) {
+	d, clock := newFile(t)
+	d.MaxAge = time.Hour
+	write(t, d, "before the restart\n")
+	d.Close()
+
+	// A new process finds the file last written two hours ago
+	old := clock.now().Add(-2 * time.Hour)
+	if err := os.Chtimes(d.Path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	restarted := NewFileDispatcher(d.Path)
+	restarted.MaxAge = time.Hour
+	restarted.now = clock.now
+	defer restarted.Close()
+	write(t, restarted, "after the restart\n")
+
+	if backups := backupNames(t, d); len(backups) != 1 {
+		t.Errorf("backups = %v, want the file from before the restart rotated", backups)
+	}
+	if got := readFile(t, d.Path); got != "after the restart\n" {
+		t.Errorf("current file = %q", got)
+	}
+}
+
+func TestFileCompress(t *testing.T) {
+	d, _ := newFile(t)
+	d.Compress = true
+	d.OnError = func(err error) { t.Errorf("OnError: %v", err) }
+
+	write(t, d, "compressed\n")
+	if err := d.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	backups := backupNames(t, d)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("backups = %v, want one .gz file", backups)
+	}
+	f, err := os.Open(filepath.Join(filepath.Dir(d.Path), backups[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil || string(data) != "compressed\n" {
+		t.Errorf("backup holds %q, %v", data, err)
+	}
+}
+
+func TestFileMaxBackups(t *testing.T) {
+	d, clock := newFile(t)
+	d.MaxBackups = 2
+
+	for _, s := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		write(t, d, s)
+		clock.advance(time.Minute)
+		if err := d.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d.Close()
+
+	backups := backupNames(t, d)
+	if len(backups) != 2 {
+		t.Fatalf("backups = %v, want 2", backups)
+	}
+	dir := filepath.Dir(d.Path)
+	if got := readFile(t, filepath.Join(dir, backups[0])) + readFile(t, filepath.Join(dir, backups[1])); got != "3\n4\n" {
+		t.Errorf("kept %q, want the newest two", got)
+	}
+}
+
+func TestFileMaxBackupAge(t *testing.T) {
+	d, clock := newFile(t)
+	d.MaxBackupAge = 24 * time.Hour
+
+	write(t, d, "old\n")
+	d.Rotate()
+	clock.advance(23 * time.Hour)
+	write(t, d, "recent\n")
+	d.Rotate()
+	d.Close()
+	if n := len(backupNames(t, d)); n != 2 {
+		t.Fatalf("%d backups, want both within MaxBackupAge", n)
+	}
+
+	clock.advance(2 * time.Hour)
+	write(t, d, "new\n")
+	d.Rotate()
+	d.Close()
+	backups := backupNames(t, d)
+	if len(backups) != 2 || backups[0] != "app-2024-05-02T11-00-00.000.log" {
+		t.Errorf("backups = %v, want the first one removed", backups)
+	}
+}
+
+func TestFileReopen(t *testing.T) {
+	d, _ := newFile(t)
+	write(t, d, "before\n")
+
+	// An external tool moves the file away
+	moved := d.Path + ".1"
+	if err := os.Rename(d.Path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	write(t, d, "after\n")
+	d.Close()
+
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("moved file = %q", got)
+	}
+	if got := readFile(t, d.Path); got != "after\n" {
+		t.Errorf("reopened file = %q", got)
+	}
+}
+
+func TestFileReopenOnSignalError(t *testing.T) {
+	d, _ := newFile(t)
+	errs := make(chan error, 1)
+	d.OnError = func(err error) { errs <- err }
+	write(t, d, "line\n")
+
+	// The directory is replaced by a file, so the reopen fails
+	dir := filepath.Dir(d.Path)
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := d.ReopenOnSignal(syscall.SIGHUP)
+	defer stop()
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("cannot signal the test process:", err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("OnError(nil)")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no error reported within 2s")
+	}
+}
+
+func TestFileNoColors(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "3")
+	d, _ := newFile(t)
+	d.Formatter = &IBMFormatter{}
+
+	msg := message.Message{ID: "SRV001", Severity: message.Info, Text: "Server starting", Links: []string{"https://example.com/SRV001"}}
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+	if got := readFile(t, d.Path); strings.Contains(got, "\x1b") || !strings.Contains(got, "SRV001") {
+		t.Errorf("log file:\n%q", got)
+	}
+}
@@ -0,0 +1,157 @@
+package dispatcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/martencassel/opsmsg/encode"
+	"github.com/martencassel/opsmsg/message"
+)
+
+const (
+	// gelfMaxChunks is the largest number of chunks a GELF message may use
+	gelfMaxChunks = 128
+	// gelfHeaderLen is the size of the header of each chunk
+	gelfHeaderLen = 12
+)
+
+// GELFDispatcher sends messages to a Graylog GELF input over UDP or TCP.
+// UDP messages larger than ChunkSize are split into GELF chunks.
+type GELFDispatcher struct {
+	// Addr is the GELF input address (host:port)
+	Addr string
+	// Network is "udp" or "tcp" (default: "udp")
+	Network string
+	// ChunkSize is the largest UDP datagram payload (default: 1420). It
+	// must leave room for the 12-byte chunk header.
+	ChunkSize int
+	// Compress gzips UDP messages
+	Compress bool
+	// Encoder sets the GELF host field
+	Encoder encode.GELF
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGELFDispatcher creates a dispatcher sending GELF over UDP to addr
+func NewGELFDispatcher(addr string) *GELFDispatcher {
+	return &GELFDispatcher{Addr: addr}
+}
+
+// Dispatch encodes msg as GELF and sends it
+func (d *GELFDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	data, err := d.Encoder.Encode(msg)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.conn == nil {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, d.network(), d.Addr)
+		if err != nil {
+			return err
+		}
+		d.conn = conn
+	}
+
+	if d.network() == "tcp" {
+		// GELF over TCP frames messages with a null byte
+		if _, err := d.conn.Write(append(data, 0)); err != nil {
+			d.conn.Close()
+			d.conn = nil
+			return err
+		}
+		return nil
+	}
+
+	if d.Compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(data)
+		zw.Close()
+		data = buf.Bytes()
+	}
+	chunks, err := gelfChunks(data, d.chunkSize())
+	if err != nil {
+		return err
+	}
+	for _, datagram := range chunks {
+		if _, err := d.conn.Write(datagram); err != nil {
+			// Redial on the next message, as for TCP
+			d.conn.Close()
+			d.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes the connection to the GELF input
+func (d *GELFDispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn = nil
+	return err
+}
+
+func (d *GELFDispatcher) network() string {
+	if d.Network == "" {
+		return "udp"
+	}
+	return d.Network
+}
+
+func (d *GELFDispatcher) chunkSize() int {
+	if d.ChunkSize > 0 {
+		return d.ChunkSize
+	}
+	return 1420
+}
+
+// gelfChunks splits data into GELF chunks of at most size bytes each,
+// header included. Data that fits is returned as a single datagram.
+func gelfChunks(data []byte, size int) ([][]byte, error) {
+	if size <= gelfHeaderLen {
+		return nil, fmt.Errorf("gelf: chunk size %d leaves no room after the %d-byte chunk header", size, gelfHeaderLen)
+	}
+	if len(data) <= size {
+		return [][]byte{data}, nil
+	}
+
+	payload := size - gelfHeaderLen
+	count := (len(data) + payload - 1) / payload
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("gelf: message of %d bytes needs more than %d chunks", len(data), gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * payload
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := make([]byte, 0, gelfHeaderLen+end-i*payload)
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data[i*payload:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
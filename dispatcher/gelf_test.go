@@ -0,0 +1,132 @@
+package dispatcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestGELFChunks(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10)
+
+	chunks, err := gelfChunks(data, 100)
+	if err != nil || len(chunks) != 1 || !bytes.Equal(chunks[0], data) {
+		t.Fatalf("data that fits: %d chunks, %v", len(chunks), err)
+	}
+
+	chunks, err = gelfChunks(data, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 4 {
+		t.Fatalf("got %d chunks, want 4 of 30 payload bytes", len(chunks))
+	}
+	var joined []byte
+	for i, c := range chunks {
+		if len(c) > 42 {
+			t.Errorf("chunk %d is %d bytes", i, len(c))
+		}
+		if c[0] != 0x1e || c[1] != 0x0f || int(c[10]) != i || int(c[11]) != len(chunks) {
+			t.Errorf("chunk %d header % x", i, c[:12])
+		}
+		if !bytes.Equal(c[2:10], chunks[0][2:10]) {
+			t.Errorf("chunk %d has a different message ID", i)
+		}
+		joined = append(joined, c[12:]...)
+	}
+	if !bytes.Equal(joined, data) {
+		t.Error("chunks do not reassemble to the message")
+	}
+
+	if _, err := gelfChunks(bytes.Repeat([]byte("x"), 129), 13); err == nil {
+		t.Error("message needing 129 chunks was accepted")
+	}
+}
+
+func TestGELFChunkSizeTooSmall(t *testing.T) {
+	for _, size := range []int{1, 12} {
+		if _, err := gelfChunks([]byte("{}"), size); err == nil || !strings.Contains(err.Error(), "chunk size") {
+			t.Errorf("size %d: error = %v", size, err)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	d := NewGELFDispatcher(conn.LocalAddr().String())
+	d.ChunkSize = 12
+	defer d.Close()
+	msg := message.Message{ID: "SRV001", Severity: message.Info, Text: "Server starting"}
+	if err := d.Dispatch(context.Background(), msg); err == nil {
+		t.Error("Dispatch with ChunkSize 12 succeeded")
+	}
+}
+
+func TestGELFUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d := NewGELFDispatcher(conn.LocalAddr().String())
+	defer d.Close()
+	msg := message.Message{ID: "SRV001", Severity: message.Info, Text: "Server starting on port {port}", Context: map[string]string{"port": "8080"}}
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var gelf map[string]interface{}
+	if err := json.Unmarshal(buf[:n], &gelf); err != nil {
+		t.Fatalf("%v: %s", err, buf[:n])
+	}
+	if gelf["short_message"] != "Server starting on port 8080" || gelf["_port"] != "8080" {
+		t.Errorf("GELF message = %v", gelf)
+	}
+}
+
+// brokenConn is a connection whose writes fail
+type brokenConn struct{ net.Conn }
+
+func (brokenConn) Write(p []byte) (int, error) { return 0, errors.New("network is unreachable") }
+func (brokenConn) Close() error                { return nil }
+
+func TestGELFRedialsAfterUDPError(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	d := NewGELFDispatcher(conn.LocalAddr().String())
+	defer d.Close()
+	d.conn = brokenConn{}
+	msg := message.Message{ID: "SRV001", Severity: message.Info, Text: "Server starting"}
+	if err := d.Dispatch(context.Background(), msg); err == nil {
+		t.Fatal("write error not returned")
+	}
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatalf("second Dispatch did not redial: %v", err)
+	}
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := conn.ReadFrom(buf); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+package dispatcher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// request is a request received by a recorder
+type request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+}
+
+// recorder is an HTTP stand-in that records requests and answers with
+// Status (default: 202 Accepted)
+type recorder struct {
+	*httptest.Server
+	Status int
+
+	mu       sync.Mutex
+	requests []request
+}
+
+func newRecorder(t *testing.T) *recorder {
+	t.Helper()
+	rec := &recorder{Status: http.StatusAccepted}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		rec.requests = append(rec.requests, request{Method: r.Method, Path: r.URL.Path, Header: r.Header.Clone(), Body: body})
+		status := rec.Status
+		rec.mu.Unlock()
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "stand-in refused the request\n")
+		}
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+// received returns the requests received so far
+func (rec *recorder) received() []request {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return append([]request(nil), rec.requests...)
+}
+
+// decode unmarshals the body of the i-th request into v
+func (rec *recorder) decode(t *testing.T, i int, v interface{}) {
+	t.Helper()
+	reqs := rec.received()
+	if len(reqs) <= i {
+		t.Fatalf("got %d requests, want at least %d", len(reqs), i+1)
+	}
+	if err := json.Unmarshal(reqs[i].Body, v); err != nil {
+		t.Fatalf("request %d: %v\n%s", i, err, reqs[i].Body)
+	}
+}
+
+func TestPostJSONErrorStatus(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusBadRequest
+
+	err := postJSON(context.Background(), nil, rec.URL, map[string]string{"X-Test": "1"}, map[string]string{"a": "b"})
+	if err == nil {
+		t.Fatal("postJSON succeeded on 400")
+	}
+	if !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "stand-in refused") {
+		t.Errorf("error %q lacks the status and response body", err)
+	}
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d requests, want 1", len(reqs))
+	}
+	if got := reqs[0].Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := reqs[0].Header.Get("X-Test"); got != "1" {
+		t.Errorf("X-Test = %q", got)
+	}
+}
@@ -2,6 +2,9 @@ package dispatcher
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/martencassel/opsmsg/message"
 	"github.com/sirupsen/logrus"
@@ -16,6 +19,26 @@ func NewLogrusDispatcher(logger *logrus.Logger) *LogrusDispatcher {
 }
 
 func (d *LogrusDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	entry := d.logger.WithFields(messageFields(msg))
+
+	switch msg.Severity {
+	case message.Info:
+		entry.Info(msg.Text)
+	case message.Warn:
+		entry.Warn(msg.Text)
+	case message.Error:
+		entry.Error(msg.Text)
+	case message.Critical:
+		entry.Fatal(msg.Text)
+	default:
+		entry.Info(msg.Text)
+	}
+
+	return nil
+}
+
+// messageFields returns the logrus fields the formatters expect for msg
+func messageFields(msg message.Message) logrus.Fields {
 	fields := logrus.Fields{
 		"id":       msg.ID,
 		"severity": string(msg.Severity),
@@ -31,20 +54,98 @@ func (d *LogrusDispatcher) Dispatch(ctx context.Context, msg message.Message) er
 		fields["help"] = msg.Help
 	}
 
-	entry := d.logger.WithFields(fields)
+	// Replies and links stay structured for formatters and hooks
+	if len(msg.Replies) > 0 {
+		fields["replies"] = msg.Replies
+	}
+	if len(msg.Links) > 0 {
+		fields["links"] = msg.Links
+	}
 
-	switch msg.Severity {
-	case message.Info:
-		entry.Info(msg.Text)
+	return fields
+}
+
+// messageEntry builds a logrus entry for msg without going through a
+// logger, so that formatters can be used directly
+func messageEntry(msg message.Message) *logrus.Entry {
+	t := msg.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return &logrus.Entry{
+		Data:    messageFields(msg),
+		Time:    t,
+		Level:   logrusLevel(msg.Severity),
+		Message: msg.Text,
+	}
+}
+
+func logrusLevel(s message.Severity) logrus.Level {
+	switch s {
 	case message.Warn:
-		entry.Warn(msg.Text)
+		return logrus.WarnLevel
 	case message.Error:
-		entry.Error(msg.Text)
+		return logrus.ErrorLevel
 	case message.Critical:
-		entry.Fatal(msg.Text)
+		return logrus.FatalLevel
 	default:
-		entry.Info(msg.Text)
+		return logrus.InfoLevel
 	}
+}
 
+// isInternalField reports whether key is one of the fields the dispatcher
+// uses to carry message metadata rather than context
+func isInternalField(key string) bool {
+	switch key {
+	case "id", "severity", "timestamp", "help", "replies", "links":
+		return true
+	}
+	return false
+}
+
+// entryMessage is the inverse of messageFields: it recovers the message
+// from a logrus entry. Entries logged without a severity field use the
+// upper-cased logrus level.
+func entryMessage(entry *logrus.Entry) message.Message {
+	msg := message.Message{
+		Text:      entry.Message,
+		Timestamp: entry.Time,
+		Context:   make(map[string]string),
+	}
+	msg.ID, _ = entry.Data["id"].(string)
+	msg.Help, _ = entry.Data["help"].(string)
+	msg.Replies = stringList(entry.Data["replies"])
+	msg.Links = stringList(entry.Data["links"])
+	if sev, ok := entry.Data["severity"].(string); ok {
+		msg.Severity = message.Severity(sev)
+	} else {
+		msg.Severity = message.Severity(strings.ToUpper(entry.Level.String()))
+	}
+
+	for k, v := range entry.Data {
+		if !isInternalField(k) {
+			msg.Context[k] = fmt.Sprint(v)
+		}
+	}
+	return msg
+}
+
+// stringList accepts the []string the dispatcher sets as well as lists
+// built by hand or decoded from JSON
+func stringList(v interface{}) []string {
+	switch v := v.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, e := range v {
+			list[i] = fmt.Sprint(e)
+		}
+		return list
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,170 @@
+package dispatcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+)
+
+// OtherID is the id label used for messages that exceed the cardinality
+// limits of MetricsDispatcher
+const OtherID = "other"
+
+// MetricsDispatcher counts dispatched messages by ID and severity and
+// forwards them to the next dispatcher. It serves the counters in the
+// Prometheus text exposition format.
+type MetricsDispatcher struct {
+	// Next receives every message after it has been counted (optional)
+	Next Dispatcher
+	// Catalog limits the id label to known IDs when set; other IDs are
+	// counted as OtherID
+	Catalog catalog.Catalog
+	// MaxIDs caps the number of distinct id labels (default: 1000); IDs
+	// beyond the cap are counted as OtherID
+	MaxIDs int
+
+	mu     sync.Mutex
+	series map[metricKey]*metricValue
+	ids    map[string]struct{}
+}
+
+type metricKey struct {
+	id       string
+	severity message.Severity
+}
+
+type metricValue struct {
+	count  uint64
+	errors uint64
+	last   time.Time
+}
+
+// NewMetricsDispatcher creates a metrics middleware in front of next
+func NewMetricsDispatcher(next Dispatcher) *MetricsDispatcher {
+	return &MetricsDispatcher{Next: next}
+}
+
+// Dispatch counts msg and forwards it to Next
+func (d *MetricsDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	t := msg.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	d.mu.Lock()
+	v := d.value(msg)
+	v.count++
+	if t.After(v.last) {
+		v.last = t
+	}
+	d.mu.Unlock()
+
+	if d.Next == nil {
+		return nil
+	}
+	err := d.Next.Dispatch(ctx, msg)
+	if err != nil {
+		d.mu.Lock()
+		d.value(msg).errors++
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// value returns the series for msg; d.mu must be held
+func (d *MetricsDispatcher) value(msg message.Message) *metricValue {
+	if d.series == nil {
+		d.series = make(map[metricKey]*metricValue)
+		d.ids = make(map[string]struct{})
+	}
+	key := metricKey{id: d.label(msg.ID), severity: msg.Severity}
+	v := d.series[key]
+	if v == nil {
+		v = &metricValue{}
+		d.series[key] = v
+	}
+	return v
+}
+
+// label applies the cardinality limits to id; d.mu must be held
+func (d *MetricsDispatcher) label(id string) string {
+	if d.Catalog != nil {
+		if _, ok := d.Catalog[id]; !ok {
+			return OtherID
+		}
+	}
+	if _, ok := d.ids[id]; ok {
+		return id
+	}
+	max := d.MaxIDs
+	if max <= 0 {
+		max = 1000
+	}
+	if len(d.ids) >= max {
+		return OtherID
+	}
+	d.ids[id] = struct{}{}
+	return id
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (d *MetricsDispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	keys := make([]metricKey, 0, len(d.series))
+	values := make(map[metricKey]metricValue, len(d.series))
+	for k, v := range d.series {
+		keys = append(keys, k)
+		values[k] = *v
+	}
+	d.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].id != keys[j].id {
+			return keys[i].id < keys[j].id
+		}
+		return keys[i].severity < keys[j].severity
+	})
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	bw := bufio.NewWriter(w)
+	defer bw.Flush()
+
+	fmt.Fprintln(bw, "# HELP opsmsg_messages_total Number of messages dispatched.")
+	fmt.Fprintln(bw, "# TYPE opsmsg_messages_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(bw, "opsmsg_messages_total%s %d\n", k.labels(), values[k].count)
+	}
+
+	fmt.Fprintln(bw, "# HELP opsmsg_message_last_emitted_timestamp_seconds Time the message was last dispatched.")
+	fmt.Fprintln(bw, "# TYPE opsmsg_message_last_emitted_timestamp_seconds gauge")
+	for _, k := range keys {
+		last := values[k].last
+		fmt.Fprintf(bw, "opsmsg_message_last_emitted_timestamp_seconds%s %.3f\n", k.labels(), float64(last.UnixNano())/1e9)
+	}
+
+	if d.Next != nil {
+		fmt.Fprintln(bw, "# HELP opsmsg_dispatch_errors_total Number of messages the next dispatcher failed to deliver.")
+		fmt.Fprintln(bw, "# TYPE opsmsg_dispatch_errors_total counter")
+		for _, k := range keys {
+			fmt.Fprintf(bw, "opsmsg_dispatch_errors_total%s %d\n", k.labels(), values[k].errors)
+		}
+	}
+}
+
+func (k metricKey) labels() string {
+	return fmt.Sprintf(`{id="%s",severity="%s"}`, escapeLabelValue(k.id), escapeLabelValue(string(k.severity)))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
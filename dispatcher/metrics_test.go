@@ -0,0 +1,114 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/message"
+)
+
+// failing is a next dispatcher that fails for the IDs in fail
+type failing map[string]bool
+
+func (f failing) Dispatch(ctx context.Context, msg message.Message) error {
+	if f[msg.ID] {
+		return errors.New("delivery failed")
+	}
+	return nil
+}
+
+// scrape returns the exposition text of d
+func scrape(t *testing.T, d *MetricsDispatcher) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestMetricsExposition(t *testing.T) {
+	d := NewMetricsDispatcher(failing{"SRV002": true})
+	ctx := context.Background()
+	at := time.Unix(1714564800, 500e6)
+	d.Dispatch(ctx, message.Message{ID: "SRV001", Severity: message.Info, Timestamp: at})
+	d.Dispatch(ctx, message.Message{ID: "SRV001", Severity: message.Info, Timestamp: at.Add(-time.Hour)})
+	if err := d.Dispatch(ctx, message.Message{ID: "SRV002", Severity: message.Error, Timestamp: at}); err == nil {
+		t.Error("the next dispatcher's error was not returned")
+	}
+
+	want := `# HELP opsmsg_messages_total Number of messages dispatched.
+# TYPE opsmsg_messages_total counter
+opsmsg_messages_total{id="SRV001",severity="INFO"} 2
+opsmsg_messages_total{id="SRV002",severity="ERROR"} 1
+# HELP opsmsg_message_last_emitted_timestamp_seconds Time the message was last dispatched.
+# TYPE opsmsg_message_last_emitted_timestamp_seconds gauge
+opsmsg_message_last_emitted_timestamp_seconds{id="SRV001",severity="INFO"} 1714564800.500
+opsmsg_message_last_emitted_timestamp_seconds{id="SRV002",severity="ERROR"} 1714564800.500
+# HELP opsmsg_dispatch_errors_total Number of messages the next dispatcher failed to deliver.
+# TYPE opsmsg_dispatch_errors_total counter
+opsmsg_dispatch_errors_total{id="SRV001",severity="INFO"} 0
+opsmsg_dispatch_errors_total{id="SRV002",severity="ERROR"} 1
+`
+	if got := scrape(t, d); got != want {
+		t.Errorf("exposition:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMetricsNoNext(t *testing.T) {
+	d := NewMetricsDispatcher(nil)
+	d.Dispatch(context.Background(), message.Message{ID: "SRV001", Severity: message.Info})
+	if got := scrape(t, d); strings.Contains(got, "opsmsg_dispatch_errors_total") {
+		t.Errorf("error counter without a next dispatcher:\n%s", got)
+	}
+}
+
+func TestMetricsLabelEscaping(t *testing.T) {
+	if got, want := escapeLabelValue("a\\b\"c\nd"), `a\\b\"c\nd`; got != want {
+		t.Errorf("escapeLabelValue = %s, want %s", got, want)
+	}
+
+	d := NewMetricsDispatcher(nil)
+	d.Dispatch(context.Background(), message.Message{ID: "ODD\"1\n", Severity: message.Warn})
+	if got := scrape(t, d); !strings.Contains(got, `opsmsg_messages_total{id="ODD\"1\n",severity="WARN"} 1`+"\n") {
+		t.Errorf("exposition:\n%s", got)
+	}
+}
+
+func TestMetricsCardinality(t *testing.T) {
+	ctx := context.Background()
+
+	d := NewMetricsDispatcher(nil)
+	d.MaxIDs = 2
+	for _, id := range []string{"SRV001", "SRV002", "SRV003", "SRV004", "SRV001"} {
+		d.Dispatch(ctx, message.Message{ID: id, Severity: message.Info})
+	}
+	got := scrape(t, d)
+	for _, line := range []string{
+		`opsmsg_messages_total{id="SRV001",severity="INFO"} 2`,
+		`opsmsg_messages_total{id="SRV002",severity="INFO"} 1`,
+		`opsmsg_messages_total{id="other",severity="INFO"} 2`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("missing %s in:\n%s", line, got)
+		}
+	}
+	if strings.Contains(got, "SRV003") || strings.Contains(got, "SRV004") {
+		t.Errorf("IDs beyond MaxIDs have their own series:\n%s", got)
+	}
+
+	d = NewMetricsDispatcher(nil)
+	d.Catalog = catalog.FromEntries([]catalog.CatalogEntry{{ID: "SRV001", Severity: "INFO", Text: "Started"}})
+	d.Dispatch(ctx, message.Message{ID: "SRV001", Severity: message.Info})
+	d.Dispatch(ctx, message.Message{ID: "user-" + time.Now().String(), Severity: message.Error})
+	got = scrape(t, d)
+	if !strings.Contains(got, `opsmsg_messages_total{id="other",severity="ERROR"} 1`) || strings.Contains(got, "user-") {
+		t.Errorf("ID outside the catalog was not folded into %s:\n%s", OtherID, got)
+	}
+}
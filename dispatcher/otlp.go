@@ -0,0 +1,292 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// OTLPLogsURL is the default OTLP/HTTP logs endpoint of a local collector
+const OTLPLogsURL = "http://localhost:4318/v1/logs"
+
+// OTLPDispatcher exports messages as OpenTelemetry log records using the
+// OTLP/HTTP JSON encoding. Records are batched and sent every Interval or
+// once MaxBatchSize records are queued.
+type OTLPDispatcher struct {
+	// URL is the collector logs endpoint (default: OTLPLogsURL)
+	URL string
+	// Client is the HTTP client used for requests (default: http.DefaultClient)
+	Client *http.Client
+	// Headers are added to every export request, e.g. for authentication
+	Headers map[string]string
+	// ServiceName is reported as the service.name resource attribute
+	// (default: the executable name)
+	ServiceName string
+	// ResourceAttributes are added to the exported resource
+	ResourceAttributes map[string]string
+	// Interval is the export interval (default: 1s)
+	Interval time.Duration
+	// MaxBatchSize triggers an export as soon as this many records are
+	// queued (default: 512)
+	MaxBatchSize int
+	// MaxQueueSize caps the records kept for the next export when an
+	// export fails; the oldest are dropped (default: 8 * MaxBatchSize)
+	MaxQueueSize int
+	// TraceFunc extracts hex-encoded trace and span IDs from the dispatch
+	// context (default: IDs stored with ContextWithTrace)
+	TraceFunc func(ctx context.Context) (traceID, spanID string)
+	// OnError receives errors of background exports
+	OnError func(error)
+
+	batch batcher[otlpLogRecord]
+}
+
+// NewOTLPDispatcher creates a dispatcher exporting to the collector logs
+// endpoint at url
+func NewOTLPDispatcher(url string) *OTLPDispatcher {
+	return &OTLPDispatcher{URL: url}
+}
+
+type traceKey struct{}
+
+type traceIDs struct {
+	traceID string
+	spanID  string
+}
+
+// ContextWithTrace returns a context carrying hex-encoded trace and span
+// IDs for OTLPDispatcher
+func ContextWithTrace(ctx context.Context, traceID, spanID string) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceIDs{traceID, spanID})
+}
+
+func traceFromContext(ctx context.Context) (string, string) {
+	ids, _ := ctx.Value(traceKey{}).(traceIDs)
+	return ids.traceID, ids.spanID
+}
+
+// Dispatch queues msg for the next export. After Close, msg is exported
+// immediately.
+func (d *OTLPDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	record := d.record(ctx, msg)
+
+	interval := d.Interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	n, ok := d.batch.add(record, interval, d.tick)
+	if !ok {
+		return d.export(ctx, []otlpLogRecord{record})
+	}
+	// Records requeued after a failed export keep the queue above the
+	// batch size, so only every full batch triggers an export
+	if n%d.maxBatchSize() == 0 {
+		return d.Flush(ctx)
+	}
+	return nil
+}
+
+// Flush exports all queued records. When the export fails the records
+// are queued again for the next export, up to MaxQueueSize.
+func (d *OTLPDispatcher) Flush(ctx context.Context) error {
+	records := d.batch.take()
+	if len(records) == 0 {
+		return nil
+	}
+	err := d.export(ctx, records)
+	if err == nil {
+		return nil
+	}
+	if dropped := d.batch.requeue(records, d.maxQueueSize()); dropped > 0 {
+		return fmt.Errorf("%w (%d records dropped)", err, dropped)
+	}
+	return fmt.Errorf("%w (%d records queued for retry)", err, len(records))
+}
+
+// Close stops the export timer and exports any queued records. Calling
+// it again does nothing.
+func (d *OTLPDispatcher) Close() error {
+	if !d.batch.close() {
+		return nil
+	}
+	records := d.batch.take()
+	if len(records) == 0 {
+		return nil
+	}
+	if err := d.export(context.Background(), records); err != nil {
+		return fmt.Errorf("%w (%d records lost)", err, len(records))
+	}
+	return nil
+}
+
+// tick runs the periodic export
+func (d *OTLPDispatcher) tick() {
+	if err := d.Flush(context.Background()); err != nil && d.OnError != nil {
+		d.OnError(err)
+	}
+}
+
+func (d *OTLPDispatcher) export(ctx context.Context, records []otlpLogRecord) error {
+	url := d.URL
+	if url == "" {
+		url = OTLPLogsURL
+	}
+	return postJSON(ctx, d.Client, url, d.Headers, d.request(records))
+}
+
+func (d *OTLPDispatcher) maxBatchSize() int {
+	if d.MaxBatchSize > 0 {
+		return d.MaxBatchSize
+	}
+	return 512
+}
+
+func (d *OTLPDispatcher) maxQueueSize() int {
+	if d.MaxQueueSize > 0 {
+		return d.MaxQueueSize
+	}
+	return 8 * d.maxBatchSize()
+}
+
+// OTLP JSON encoding of opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest
+
+type otlpExportRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func (d *OTLPDispatcher) record(ctx context.Context, msg message.Message) otlpLogRecord {
+	t := msg.Timestamp
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	attrs := []otlpKeyValue{
+		otlpString("event.name", msg.ID),
+		otlpString("opsmsg.id", msg.ID),
+		otlpString("opsmsg.template", msg.Text),
+	}
+	if msg.Help != "" {
+		attrs = append(attrs, otlpString("opsmsg.help", msg.Help))
+	}
+	keys := make([]string, 0, len(msg.Context))
+	for k := range msg.Context {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, otlpString(k, msg.Context[k]))
+	}
+
+	traceFunc := d.TraceFunc
+	if traceFunc == nil {
+		traceFunc = traceFromContext
+	}
+	traceID, spanID := traceFunc(ctx)
+
+	return otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(t.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverityNumber(msg.Severity),
+		SeverityText:         string(msg.Severity),
+		Body:                 otlpAnyValue{StringValue: msg.Render()},
+		Attributes:           attrs,
+		TraceID:              traceID,
+		SpanID:               spanID,
+	}
+}
+
+func (d *OTLPDispatcher) request(records []otlpLogRecord) otlpExportRequest {
+	service := d.ServiceName
+	if service == "" {
+		service = "unknown_service"
+		if exe, err := os.Executable(); err == nil {
+			service = "unknown_service:" + filepath.Base(exe)
+		}
+	}
+
+	attrs := []otlpKeyValue{otlpString("service.name", service)}
+	keys := make([]string, 0, len(d.ResourceAttributes))
+	for k := range d.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, otlpString(k, d.ResourceAttributes[k]))
+	}
+
+	return otlpExportRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{Attributes: attrs},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "github.com/martencassel/opsmsg"},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+func otlpString(key, value string) otlpKeyValue {
+	return otlpKeyValue{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
+
+// otlpSeverityNumber maps a severity to the OpenTelemetry SeverityNumber
+// at the base of its range
+func otlpSeverityNumber(s message.Severity) int {
+	switch s {
+	case message.Info:
+		return 9
+	case message.Warn:
+		return 13
+	case message.Error:
+		return 17
+	case message.Critical:
+		return 21
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,222 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func otlpMessage(port string) message.Message {
+	return message.Message{
+		ID:        "SRV002",
+		Severity:  message.Error,
+		Text:      "Failed to bind to port {port}",
+		Context:   map[string]string{"port": port},
+		Timestamp: time.Unix(1714564800, 0),
+		Help:      "Cause: port in use.",
+	}
+}
+
+// newOTLP returns a dispatcher for the collector stand-in that only
+// exports when flushed
+func newOTLP(rec *recorder) *OTLPDispatcher {
+	d := NewOTLPDispatcher(rec.URL + "/v1/logs")
+	d.Interval = time.Hour
+	d.ServiceName = "checkout"
+	d.Headers = map[string]string{"Authorization": "Bearer token"}
+	return d
+}
+
+// exported returns the log records of the i-th export request
+func exported(t *testing.T, rec *recorder, i int) []otlpLogRecord {
+	t.Helper()
+	var req otlpExportRequest
+	rec.decode(t, i, &req)
+	if len(req.ResourceLogs) != 1 || len(req.ResourceLogs[0].ScopeLogs) != 1 {
+		t.Fatalf("request %d: unexpected shape %+v", i, req)
+	}
+	return req.ResourceLogs[0].ScopeLogs[0].LogRecords
+}
+
+func TestOTLPExport(t *testing.T) {
+	rec := newRecorder(t)
+	d := newOTLP(rec)
+	d.ResourceAttributes = map[string]string{"deployment.environment": "prod"}
+
+	ctx := ContextWithTrace(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+	if err := d.Dispatch(ctx, otlpMessage("8080")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.received()); n != 0 {
+		t.Fatalf("%d exports before Close", n)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reqs := rec.received()
+	if len(reqs) != 1 {
+		t.Fatalf("got %d exports, want 1", len(reqs))
+	}
+	if reqs[0].Path != "/v1/logs" || reqs[0].Header.Get("Authorization") != "Bearer token" {
+		t.Errorf("path %q, headers %v", reqs[0].Path, reqs[0].Header)
+	}
+
+	var req otlpExportRequest
+	rec.decode(t, 0, &req)
+	resource := req.ResourceLogs[0].Resource.Attributes
+	if len(resource) != 2 || resource[0] != otlpString("service.name", "checkout") || resource[1] != otlpString("deployment.environment", "prod") {
+		t.Errorf("resource attributes = %+v", resource)
+	}
+
+	records := exported(t, rec, 0)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1", len(records))
+	}
+	r := records[0]
+	if r.TimeUnixNano != "1714564800000000000" {
+		t.Errorf("timeUnixNano = %s", r.TimeUnixNano)
+	}
+	if r.SeverityNumber != 17 || r.SeverityText != "ERROR" {
+		t.Errorf("severity = %d %s", r.SeverityNumber, r.SeverityText)
+	}
+	if r.Body.StringValue != "Failed to bind to port 8080" {
+		t.Errorf("body = %q", r.Body.StringValue)
+	}
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" {
+		t.Errorf("trace, span = %s, %s", r.TraceID, r.SpanID)
+	}
+	want := []otlpKeyValue{
+		otlpString("event.name", "SRV002"),
+		otlpString("opsmsg.id", "SRV002"),
+		otlpString("opsmsg.template", "Failed to bind to port {port}"),
+		otlpString("opsmsg.help", "Cause: port in use."),
+		otlpString("port", "8080"),
+	}
+	if len(r.Attributes) != len(want) {
+		t.Fatalf("attributes = %+v", r.Attributes)
+	}
+	for i := range want {
+		if r.Attributes[i] != want[i] {
+			t.Errorf("attribute %d = %+v, want %+v", i, r.Attributes[i], want[i])
+		}
+	}
+}
+
+func TestOTLPMaxBatchSize(t *testing.T) {
+	rec := newRecorder(t)
+	d := newOTLP(rec)
+	d.MaxBatchSize = 2
+	defer d.Close()
+
+	for _, port := range []string{"1", "2", "3"} {
+		if err := d.Dispatch(context.Background(), otlpMessage(port)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(rec.received()); n != 1 {
+		t.Fatalf("got %d exports, want 1 for the full batch", n)
+	}
+	if n := len(exported(t, rec, 0)); n != 2 {
+		t.Errorf("batch has %d records, want 2", n)
+	}
+}
+
+func TestOTLPRequeue(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusServiceUnavailable
+	d := newOTLP(rec)
+	defer d.Close()
+
+	ctx := context.Background()
+	d.Dispatch(ctx, otlpMessage("1"))
+	d.Dispatch(ctx, otlpMessage("2"))
+	err := d.Flush(ctx)
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "2 records queued for retry") {
+		t.Fatalf("Flush error = %v", err)
+	}
+
+	rec.mu.Lock()
+	rec.Status = http.StatusOK
+	rec.mu.Unlock()
+	d.Dispatch(ctx, otlpMessage("3"))
+	if err := d.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	records := exported(t, rec, 1)
+	var ports []string
+	for _, r := range records {
+		ports = append(ports, r.Attributes[len(r.Attributes)-1].Value.StringValue)
+	}
+	if got := strings.Join(ports, ","); got != "1,2,3" {
+		t.Errorf("retried export has ports %s, want 1,2,3", got)
+	}
+}
+
+func TestOTLPRequeueDrops(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusServiceUnavailable
+	d := newOTLP(rec)
+	d.MaxQueueSize = 2
+	defer d.Close()
+
+	ctx := context.Background()
+	for _, port := range []string{"1", "2", "3"} {
+		d.Dispatch(ctx, otlpMessage(port))
+	}
+	err := d.Flush(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 records dropped") {
+		t.Errorf("Flush error = %v, want the dropped count", err)
+	}
+}
+
+func TestOTLPCloseTwice(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusBadGateway
+	d := newOTLP(rec)
+
+	d.Dispatch(context.Background(), otlpMessage("1"))
+	err := d.Close()
+	if err == nil || !strings.Contains(err.Error(), "1 records lost") {
+		t.Errorf("Close error = %v, want the lost count", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+
+	// Nothing would export a batch any more, so this is sent at once
+	err = d.Dispatch(context.Background(), otlpMessage("2"))
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("Dispatch after Close = %v, want the export error", err)
+	}
+	if n := len(rec.received()); n != 2 {
+		t.Errorf("got %d exports, want 2", n)
+	}
+}
+
+func TestOTLPBackgroundExport(t *testing.T) {
+	rec := newRecorder(t)
+	d := newOTLP(rec)
+	d.Interval = 10 * time.Millisecond
+	errs := make(chan error, 10)
+	d.OnError = func(err error) { errs <- err }
+	defer d.Close()
+
+	d.Dispatch(context.Background(), otlpMessage("1"))
+	deadline := time.Now().Add(2 * time.Second)
+	for len(rec.received()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no export within 2s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case err := <-errs:
+		t.Errorf("OnError: %v", err)
+	default:
+	}
+}
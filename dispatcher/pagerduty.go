@@ -0,0 +1,167 @@
+package dispatcher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// PagerDutyEventsURL is the PagerDuty Events API v2 enqueue endpoint
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDutyDispatcher sends messages to the PagerDuty Events API v2
+type PagerDutyDispatcher struct {
+	// RoutingKey is the integration key of the PagerDuty service
+	RoutingKey string
+	// URL overrides the events endpoint (default: PagerDutyEventsURL)
+	URL string
+	// Client is the HTTP client used for requests (default: http.DefaultClient)
+	Client *http.Client
+	// Source identifies the affected system (default: hostname)
+	Source string
+	// MinSeverity is the lowest severity that triggers an incident
+	MinSeverity message.Severity
+	// DedupFields restricts which context keys contribute to the dedup key.
+	// All context keys are used when empty.
+	DedupFields []string
+}
+
+// NewPagerDutyDispatcher creates a dispatcher that triggers incidents for
+// CRITICAL messages
+func NewPagerDutyDispatcher(routingKey string) *PagerDutyDispatcher {
+	return &PagerDutyDispatcher{
+		RoutingKey:  routingKey,
+		MinSeverity: message.Critical,
+	}
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	Timestamp     string            `json:"timestamp,omitempty"`
+	Component     string            `json:"component,omitempty"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+// Dispatch triggers an incident for messages at or above MinSeverity.
+// Less severe messages are ignored.
+func (d *PagerDutyDispatcher) Dispatch(ctx context.Context, msg message.Message) error {
+	if msg.Severity.Rank() < d.MinSeverity.Rank() {
+		return nil
+	}
+	return d.Trigger(ctx, msg)
+}
+
+// Trigger opens (or re-triggers) the incident for msg
+func (d *PagerDutyDispatcher) Trigger(ctx context.Context, msg message.Message) error {
+	details := make(map[string]string, len(msg.Context)+3)
+	for k, v := range msg.Context {
+		details[k] = v
+	}
+	details["id"] = msg.ID
+	details["template"] = msg.Text
+	if msg.Help != "" {
+		details["help"] = msg.Help
+	}
+
+	payload := &pagerDutyPayload{
+		Summary:       msg.ID + ": " + msg.Render(),
+		Source:        d.source(),
+		Severity:      pagerDutySeverity(msg.Severity),
+		Component:     msg.ID,
+		CustomDetails: details,
+	}
+	if !msg.Timestamp.IsZero() {
+		payload.Timestamp = msg.Timestamp.Format(time.RFC3339)
+	}
+
+	return d.send(ctx, "trigger", msg, payload)
+}
+
+// Acknowledge acknowledges the incident previously triggered for msg
+func (d *PagerDutyDispatcher) Acknowledge(ctx context.Context, msg message.Message) error {
+	return d.send(ctx, "acknowledge", msg, nil)
+}
+
+// Resolve resolves the incident previously triggered for msg
+func (d *PagerDutyDispatcher) Resolve(ctx context.Context, msg message.Message) error {
+	return d.send(ctx, "resolve", msg, nil)
+}
+
+// DedupKey returns the key that ties trigger, acknowledge and resolve
+// events for msg to the same incident
+func (d *PagerDutyDispatcher) DedupKey(msg message.Message) string {
+	// Copy, so sorting does not touch the configured slice while other
+	// goroutines dispatch
+	keys := append([]string(nil), d.DedupFields...)
+	if len(keys) == 0 {
+		keys = make([]string, 0, len(msg.Context))
+		for k := range msg.Context {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return msg.ID
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write([]byte(k))
+		h.Write([]byte{'='})
+		h.Write([]byte(msg.Context[k]))
+		h.Write([]byte{'\n'})
+	}
+	return msg.ID + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (d *PagerDutyDispatcher) send(ctx context.Context, action string, msg message.Message, payload *pagerDutyPayload) error {
+	url := d.URL
+	if url == "" {
+		url = PagerDutyEventsURL
+	}
+	event := pagerDutyEvent{
+		RoutingKey:  d.RoutingKey,
+		EventAction: action,
+		DedupKey:    d.DedupKey(msg),
+		Payload:     payload,
+	}
+	return postJSON(ctx, d.Client, url, nil, event)
+}
+
+func (d *PagerDutyDispatcher) source() string {
+	if d.Source != "" {
+		return d.Source
+	}
+	if host, err := os.Hostname(); err == nil {
+		return host
+	}
+	return "opsmsg"
+}
+
+func pagerDutySeverity(s message.Severity) string {
+	switch s {
+	case message.Critical:
+		return "critical"
+	case message.Error:
+		return "error"
+	case message.Warn:
+		return "warning"
+	default:
+		return "info"
+	}
+}
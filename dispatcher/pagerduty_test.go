@@ -0,0 +1,173 @@
+package dispatcher
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func pagerDutyMessage() message.Message {
+	return message.Message{
+		ID:        "SRV002",
+		Severity:  message.Critical,
+		Text:      "Failed to bind to port {port}",
+		Context:   map[string]string{"port": "8080", "host": "web-1"},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Help:      "Cause: port in use. Recovery: free the port.",
+	}
+}
+
+func TestPagerDutyTrigger(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewPagerDutyDispatcher("routing-key")
+	d.URL = rec.URL
+	d.Source = "web-1"
+	msg := pagerDutyMessage()
+
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	var event pagerDutyEvent
+	rec.decode(t, 0, &event)
+
+	if event.RoutingKey != "routing-key" || event.EventAction != "trigger" {
+		t.Errorf("routing_key, event_action = %q, %q", event.RoutingKey, event.EventAction)
+	}
+	if event.DedupKey != d.DedupKey(msg) {
+		t.Errorf("dedup_key = %q, want %q", event.DedupKey, d.DedupKey(msg))
+	}
+	p := event.Payload
+	if p == nil {
+		t.Fatal("trigger without payload")
+	}
+	want := pagerDutyPayload{
+		Summary:   "SRV002: Failed to bind to port 8080",
+		Source:    "web-1",
+		Severity:  "critical",
+		Timestamp: "2024-05-01T12:00:00Z",
+		Component: "SRV002",
+	}
+	got := *p
+	got.CustomDetails = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %+v, want %+v", got, want)
+	}
+	for k, v := range map[string]string{"port": "8080", "host": "web-1", "id": "SRV002", "template": msg.Text, "help": msg.Help} {
+		if p.CustomDetails[k] != v {
+			t.Errorf("custom_details[%q] = %q, want %q", k, p.CustomDetails[k], v)
+		}
+	}
+}
+
+func TestPagerDutyAcknowledgeResolve(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewPagerDutyDispatcher("routing-key")
+	d.URL = rec.URL
+	msg := pagerDutyMessage()
+
+	if err := d.Acknowledge(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Resolve(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	for i, action := range []string{"acknowledge", "resolve"} {
+		var event pagerDutyEvent
+		rec.decode(t, i, &event)
+		if event.EventAction != action {
+			t.Errorf("event %d: event_action = %q, want %q", i, event.EventAction, action)
+		}
+		if event.DedupKey != d.DedupKey(msg) {
+			t.Errorf("event %d: dedup_key = %q, want the trigger's %q", i, event.DedupKey, d.DedupKey(msg))
+		}
+		if event.Payload != nil {
+			t.Errorf("event %d: unexpected payload %+v", i, event.Payload)
+		}
+	}
+}
+
+func TestPagerDutyMinSeverity(t *testing.T) {
+	rec := newRecorder(t)
+	d := NewPagerDutyDispatcher("routing-key")
+	d.URL = rec.URL
+	msg := pagerDutyMessage()
+	msg.Severity = message.Error
+
+	if err := d.Dispatch(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(rec.received()); n != 0 {
+		t.Errorf("ERROR message below MinSeverity sent %d requests", n)
+	}
+}
+
+func TestPagerDutyDedupKey(t *testing.T) {
+	d := NewPagerDutyDispatcher("routing-key")
+	msg := pagerDutyMessage()
+
+	if got := d.DedupKey(message.Message{ID: "SRV001"}); got != "SRV001" {
+		t.Errorf("without context: %q, want the bare ID", got)
+	}
+	key := d.DedupKey(msg)
+	if !strings.HasPrefix(key, "SRV002-") || len(key) != len("SRV002-")+16 {
+		t.Errorf("key %q is not ID-<16 hex digits>", key)
+	}
+
+	other := pagerDutyMessage()
+	other.Context = map[string]string{"port": "9090", "host": "web-1"}
+	if d.DedupKey(other) == key {
+		t.Error("different context values share a key")
+	}
+	other.Timestamp = time.Time{}
+	other.Context = map[string]string{"host": "web-1", "port": "8080"}
+	if d.DedupKey(other) != key {
+		t.Error("key depends on more than the ID and context")
+	}
+
+	// Only DedupFields count, in any order
+	d.DedupFields = []string{"port", "host"}
+	restricted := d.DedupKey(msg)
+	other.Context = map[string]string{"port": "8080", "host": "web-1", "pid": "42"}
+	if d.DedupKey(other) != restricted {
+		t.Error("context keys outside DedupFields changed the key")
+	}
+	if d.DedupFields[0] != "port" || d.DedupFields[1] != "host" {
+		t.Errorf("DedupKey reordered DedupFields: %v", d.DedupFields)
+	}
+}
+
+func TestPagerDutyDedupKeyConcurrent(t *testing.T) {
+	d := NewPagerDutyDispatcher("routing-key")
+	d.DedupFields = []string{"port", "host"}
+	msg := pagerDutyMessage()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				d.DedupKey(msg)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPagerDutyErrorStatus(t *testing.T) {
+	rec := newRecorder(t)
+	rec.Status = http.StatusTooManyRequests
+	d := NewPagerDutyDispatcher("routing-key")
+	d.URL = rec.URL
+
+	err := d.Dispatch(context.Background(), pagerDutyMessage())
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Dispatch error = %v, want the 429 status", err)
+	}
+}
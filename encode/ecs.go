@@ -0,0 +1,97 @@
+package encode
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// ECSVersion is the Elastic Common Schema version the ECS encoder follows
+const ECSVersion = "8.11.0"
+
+// ECS encodes messages as Elastic Common Schema JSON documents. The message
+// ID is stored in event.code and context fields become labels.
+type ECS struct {
+	// ServiceName sets service.name when not empty
+	ServiceName string
+	// Dataset sets event.dataset when not empty
+	Dataset string
+}
+
+type ecsDocument struct {
+	Timestamp string            `json:"@timestamp"`
+	Message   string            `json:"message"`
+	Log       ecsLog            `json:"log"`
+	Event     ecsEvent          `json:"event"`
+	ECS       ecsVersion        `json:"ecs"`
+	Service   *ecsService       `json:"service,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Opsmsg    opsmsgFields      `json:"opsmsg"`
+}
+
+type ecsLog struct {
+	Level string `json:"level"`
+}
+
+type ecsEvent struct {
+	Code     string `json:"code"`
+	Kind     string `json:"kind"`
+	Severity int    `json:"severity"`
+	Dataset  string `json:"dataset,omitempty"`
+}
+
+type ecsVersion struct {
+	Version string `json:"version"`
+}
+
+type ecsService struct {
+	Name string `json:"name"`
+}
+
+// opsmsgFields keeps the catalog data that has no ECS equivalent
+type opsmsgFields struct {
+	ID       string   `json:"id"`
+	Severity string   `json:"severity"`
+	Template string   `json:"template"`
+	Help     string   `json:"help,omitempty"`
+	Replies  []string `json:"replies,omitempty"`
+	Links    []string `json:"links,omitempty"`
+}
+
+// Encode returns msg as one ECS JSON document without a trailing newline
+func (e ECS) Encode(msg message.Message) ([]byte, error) {
+	doc := ecsDocument{
+		Timestamp: timestamp(msg).UTC().Format(time.RFC3339Nano),
+		Message:   msg.Render(),
+		Log:       ecsLog{Level: strings.ToLower(string(msg.Severity))},
+		Event: ecsEvent{
+			Code:     msg.ID,
+			Kind:     "event",
+			Severity: msg.Severity.Rank(),
+			Dataset:  e.Dataset,
+		},
+		ECS:    ecsVersion{Version: ECSVersion},
+		Labels: msg.Context,
+		Opsmsg: opsmsgFields{
+			ID:       msg.ID,
+			Severity: string(msg.Severity),
+			Template: msg.Text,
+			Help:     msg.Help,
+			Replies:  msg.Replies,
+			Links:    msg.Links,
+		},
+	}
+	if e.ServiceName != "" {
+		doc.Service = &ecsService{Name: e.ServiceName}
+	}
+	return json.Marshal(doc)
+}
+
+func timestamp(msg message.Message) time.Time {
+	if msg.Timestamp.IsZero() {
+		return time.Now()
+	}
+	return msg.Timestamp
+}
@@ -0,0 +1,26 @@
+// Package encode serializes messages into machine-readable log formats:
+// Elastic Common Schema JSON, GELF and logfmt. Every format carries the
+// rendered text together with the message ID, template, severity and help,
+// so the opsmsg semantics survive shipping to a log store.
+package encode
+
+import (
+	"sort"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Encoder serializes a message into a single record
+type Encoder interface {
+	Encode(msg message.Message) ([]byte, error)
+}
+
+// sortedKeys returns the keys of ctx in alphabetical order
+func sortedKeys(ctx map[string]string) []string {
+	keys := make([]string, 0, len(ctx))
+	for k := range ctx {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
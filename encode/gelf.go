@@ -0,0 +1,101 @@
+package encode
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// GELF encodes messages as Graylog Extended Log Format 1.1 documents.
+// Context fields become additional fields prefixed with "_".
+type GELF struct {
+	// Host sets the host field (default: hostname)
+	Host string
+}
+
+// Encode returns msg as one GELF JSON document without a trailing newline
+func (e GELF) Encode(msg message.Message) ([]byte, error) {
+	host := e.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+
+	doc := map[string]interface{}{
+		"version":       "1.1",
+		"host":          host,
+		"short_message": msg.Render(),
+		"timestamp":     float64(timestamp(msg).UnixNano()/1e6) / 1e3,
+		"level":         SyslogLevel(msg.Severity),
+		"_message_id":   msg.ID,
+		"_severity":     string(msg.Severity),
+		"_template":     msg.Text,
+	}
+	if msg.Help != "" {
+		doc["full_message"] = msg.Help
+		doc["_help"] = msg.Help
+	}
+	if len(msg.Replies) > 0 {
+		doc["_replies"] = strings.Join(msg.Replies, "\n")
+	}
+	if len(msg.Links) > 0 {
+		doc["_links"] = strings.Join(msg.Links, "\n")
+	}
+	for _, k := range sortedKeys(msg.Context) {
+		doc[gelfFieldName(k)] = msg.Context[k]
+	}
+	return json.Marshal(doc)
+}
+
+// gelfOwnFields are the additional fields Encode sets itself, without
+// the leading "_"
+var gelfOwnFields = map[string]bool{
+	"message_id": true, "severity": true, "template": true,
+	"help": true, "replies": true, "links": true,
+}
+
+// gelfFieldName maps a context key to a valid GELF additional field name.
+// Keys that would collide with "_id", which GELF reserves, or with the
+// fields Encode sets are written as "_ctx_<key>", and so are keys that
+// would read as such a renamed one: ctx_id becomes _ctx_ctx_id.
+func gelfFieldName(key string) string {
+	var b strings.Builder
+	b.WriteString("_")
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	name := b.String()
+	if gelfRenamed(name[1:]) {
+		return "_ctx" + name
+	}
+	return name
+}
+
+// gelfRenamed reports whether context key name is written with a "ctx_"
+// prefix
+func gelfRenamed(name string) bool {
+	if rest, ok := strings.CutPrefix(name, "ctx_"); ok {
+		return gelfRenamed(rest)
+	}
+	return name == "id" || gelfOwnFields[name]
+}
+
+// SyslogLevel maps a severity to the syslog level used by GELF
+func SyslogLevel(s message.Severity) int {
+	switch s {
+	case message.Critical:
+		return 2
+	case message.Error:
+		return 3
+	case message.Warn:
+		return 4
+	default:
+		return 6
+	}
+}
@@ -0,0 +1,107 @@
+package encode
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Logfmt encodes messages as a single logfmt line. Context fields follow
+// the fixed fields in placeholder order, then alphabetically.
+type Logfmt struct {
+	// TimestampFormat sets the time format (default: RFC3339)
+	TimestampFormat string
+}
+
+// Encode returns msg as one logfmt line without a trailing newline
+func (e Logfmt) Encode(msg message.Message) ([]byte, error) {
+	format := e.TimestampFormat
+	if format == "" {
+		format = time.RFC3339
+	}
+
+	var b strings.Builder
+	pair := func(k, v string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(logfmtKey(k))
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(v))
+	}
+
+	pair("time", timestamp(msg).Format(format))
+	pair("level", strings.ToLower(string(msg.Severity)))
+	pair("id", msg.ID)
+	pair("severity", string(msg.Severity))
+	pair("msg", msg.Render())
+	pair("template", msg.Text)
+	if msg.Help != "" {
+		pair("help", msg.Help)
+	}
+	if len(msg.Replies) > 0 {
+		pair("replies", strings.Join(msg.Replies, "|"))
+	}
+	if len(msg.Links) > 0 {
+		pair("links", strings.Join(msg.Links, " "))
+	}
+	for _, k := range msg.ContextKeys() {
+		key := k
+		if logfmtReserved[key] {
+			key = "ctx_" + key
+		}
+		pair(key, msg.Context[k])
+	}
+	return []byte(b.String()), nil
+}
+
+// logfmtReserved lists the keys of the fixed fields; context fields with
+// these names are prefixed with "ctx_"
+var logfmtReserved = map[string]bool{
+	"time": true, "level": true, "id": true, "severity": true,
+	"msg": true, "template": true, "help": true, "replies": true,
+	"links": true,
+}
+
+// logfmtKey replaces characters that would break the key=value syntax
+func logfmtKey(k string) string {
+	if k == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	if !strings.ContainsAny(v, " =\"\\\t\n\r") {
+		return v
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range v {
+		switch r {
+		case '"', '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,28 @@
+package encode
+
+import (
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestLogfmt(t *testing.T) {
+	msg := message.Message{
+		ID:        "SRV002",
+		Severity:  message.Error,
+		Text:      "Failed to bind {host}:{port}",
+		Context:   map[string]string{"port": "8080", "host": "web-1", "zone": "eu north", "id": "x"},
+		Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+	data, err := Logfmt{}.Encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Placeholder fields come first in text order, then the rest
+	// alphabetically; context keys that clash with fixed fields get ctx_
+	want := `time=2024-05-01T12:00:00Z level=error id=SRV002 severity=ERROR msg="Failed to bind web-1:8080" template="Failed to bind {host}:{port}" host=web-1 port=8080 ctx_id=x zone="eu north"`
+	if string(data) != want {
+		t.Errorf("got  %s\nwant %s", data, want)
+	}
+}
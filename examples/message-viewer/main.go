@@ -10,26 +10,16 @@ import (
 
 	"github.com/martencassel/opsmsg/catalog"
 	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/render"
 )
 
 const (
-	// ANSI color codes
+	// ANSI color codes for the viewer chrome
 	colorReset  = "\033[0m"
 	colorOrange = "\033[38;5;208m"
-	colorYellow = "\033[38;5;226m"
-	colorRed    = "\033[38;5;196m"
-	colorCyan   = "\033[38;5;51m"
 	colorGray   = "\033[38;5;240m"
 	colorBright = "\033[1m"
 	colorDim    = "\033[2m"
-
-	// Box drawing characters
-	boxTopLeft     = "╭"
-	boxTopRight    = "╮"
-	boxBottomLeft  = "╰"
-	boxBottomRight = "╯"
-	boxHorizontal  = "─"
-	boxVertical    = "│"
 )
 
 type MessageViewer struct {
@@ -48,225 +38,12 @@ func (v *MessageViewer) AddMessage(msg message.Message) {
 	v.messages = append(v.messages, msg)
 }
 
-func (v *MessageViewer) getSeverityColor(severity message.Severity) string {
-	switch severity {
-	case message.Critical:
-		return colorRed
-	case message.Error:
-		return colorRed
-	case message.Warn:
-		return colorYellow
-	case message.Info:
-		return colorCyan
-	default:
-		return colorOrange
-	}
-}
-
 func (v *MessageViewer) renderMessage(msg message.Message) string {
 	var sb strings.Builder
-
-	// Top border
-	width := 80
-	sb.WriteString(colorOrange)
-	sb.WriteString(boxTopLeft)
-	sb.WriteString(strings.Repeat(boxHorizontal, width-2))
-	sb.WriteString(boxTopRight)
-	sb.WriteString(colorReset)
-	sb.WriteString("\n")
-
-	// Timestamp, ID and Severity line
-	timestamp := msg.Timestamp.Format("2006-01-02T15:04:05Z")
-	severityColor := v.getSeverityColor(msg.Severity)
-
-	sb.WriteString(colorOrange)
-	sb.WriteString(boxVertical)
-	sb.WriteString(colorReset)
-	sb.WriteString(" ")
-	sb.WriteString(colorGray)
-	sb.WriteString("[")
-	sb.WriteString(timestamp)
-	sb.WriteString("]")
-	sb.WriteString(colorReset)
-	sb.WriteString(" ")
-	sb.WriteString(colorBright)
-	sb.WriteString(colorOrange)
-	sb.WriteString(msg.ID)
-	sb.WriteString(colorReset)
-	sb.WriteString(" ")
-	sb.WriteString(severityColor)
-	sb.WriteString("(")
-	sb.WriteString(string(msg.Severity))
-	sb.WriteString(")")
-	sb.WriteString(colorReset)
-
-	// Pad to width
-	currentLen := len(timestamp) + len(msg.ID) + len(msg.Severity) + 7 // brackets, parens, spaces
-	padding := width - currentLen - 4
-	if padding < 0 {
-		padding = 0
-	}
-	sb.WriteString(strings.Repeat(" ", padding))
-	sb.WriteString(colorOrange)
-	sb.WriteString(boxVertical)
-	sb.WriteString(colorReset)
-	sb.WriteString("\n")
-
-	// Message text - wrap if needed
-	text := msg.Text
-	for k, v := range msg.Context {
-		text = strings.ReplaceAll(text, "{"+k+"}", v)
-	}
-
-	textLines := wrapText(text, width-4)
-	for _, line := range textLines {
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString(" ")
-		sb.WriteString(colorBright)
-		sb.WriteString(line)
-		sb.WriteString(colorReset)
-
-		textLen := len(line)
-		padding = width - textLen - 4
-		if padding < 0 {
-			padding = 0
-		}
-		sb.WriteString(strings.Repeat(" ", padding))
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString("\n")
-	}
-
-	// Context section (if any)
-	if len(msg.Context) > 0 {
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString(strings.Repeat(" ", width-2))
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString("\n")
-
-		for k, v := range msg.Context {
-			sb.WriteString(colorOrange)
-			sb.WriteString(boxVertical)
-			sb.WriteString(colorReset)
-			sb.WriteString("    ")
-			sb.WriteString(colorDim)
-			sb.WriteString(k)
-			sb.WriteString("=")
-			sb.WriteString(v)
-			sb.WriteString(colorReset)
-
-			contextLen := len(k) + len(v) + 5 // "    ", "=", and spaces
-			padding = width - contextLen - 2
-			if padding < 0 {
-				padding = 0
-			}
-			sb.WriteString(strings.Repeat(" ", padding))
-			sb.WriteString(colorOrange)
-			sb.WriteString(boxVertical)
-			sb.WriteString(colorReset)
-			sb.WriteString("\n")
-		}
-	}
-
-	// Help section
-	if msg.Help != "" {
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString(strings.Repeat(" ", width-2))
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString("\n")
-
-		sb.WriteString(colorOrange)
-		sb.WriteString(boxVertical)
-		sb.WriteString(colorReset)
-		sb.WriteString(" ")
-		sb.WriteString(colorYellow)
-		sb.WriteString("Help:")
-		sb.WriteString(colorReset)
-		sb.WriteString(" ")
-		sb.WriteString(colorDim)
-
-		// Word wrap help text
-		helpLines := wrapText(msg.Help, width-10)
-		for i, line := range helpLines {
-			if i > 0 {
-				sb.WriteString(colorOrange)
-				sb.WriteString(boxVertical)
-				sb.WriteString(colorReset)
-				sb.WriteString("       ")
-			}
-			sb.WriteString(line)
-			sb.WriteString(colorReset)
-
-			lineLen := len(line)
-			var totalLen int
-			if i == 0 {
-				totalLen = 1 + 6 + 1 + lineLen + 1 + 1 // │ + "Help: " + " " + line + " " + │
-			} else {
-				totalLen = 1 + 7 + lineLen + 1 + 1 // │ + "       " + line + " " + │
-			}
-			padding = width - totalLen
-			if padding < 0 {
-				padding = 0
-			}
-			sb.WriteString(strings.Repeat(" ", padding))
-			sb.WriteString(colorOrange)
-			sb.WriteString(boxVertical)
-			sb.WriteString(colorReset)
-			sb.WriteString("\n")
-		}
-	}
-
-	// Bottom border
-	sb.WriteString(colorOrange)
-	sb.WriteString(boxBottomLeft)
-	sb.WriteString(strings.Repeat(boxHorizontal, width-2))
-	sb.WriteString(boxBottomRight)
-	sb.WriteString(colorReset)
-	sb.WriteString("\n")
-
+	render.Box{Width: 80}.Render(&sb, msg)
 	return sb.String()
 }
 
-func wrapText(text string, width int) []string {
-	words := strings.Fields(text)
-	if len(words) == 0 {
-		return []string{""}
-	}
-
-	var lines []string
-	var currentLine strings.Builder
-
-	for _, word := range words {
-		if currentLine.Len() == 0 {
-			currentLine.WriteString(word)
-		} else if currentLine.Len()+len(word)+1 <= width {
-			currentLine.WriteString(" ")
-			currentLine.WriteString(word)
-		} else {
-			lines = append(lines, currentLine.String())
-			currentLine.Reset()
-			currentLine.WriteString(word)
-		}
-	}
-
-	if currentLine.Len() > 0 {
-		lines = append(lines, currentLine.String())
-	}
-
-	return lines
-}
-
 func (v *MessageViewer) Show() {
 	if len(v.messages) == 0 {
 		fmt.Println("No messages to display")
@@ -4,7 +4,6 @@ go 1.21
 
 require (
 	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8
 	gopkg.in/yaml.v3 v3.0.1
 )
-
-require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
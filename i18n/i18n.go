@@ -0,0 +1,287 @@
+// Package i18n moves catalog wording to and from translators. It writes
+// the text and help of every entry as gettext PO or XLIFF 1.2 and 2.0
+// units, reads translated files back into locale catalogs and reports
+// how complete a locale catalog is.
+//
+// Placeholders such as {port} and the Cause: and Recovery: labels of the
+// help text are protected: XLIFF marks them as inline codes, PO lists
+// them in a comment and flags the entry python-brace-format, and
+// translations that change them are rejected on import.
+//
+// A locale catalog is an ordinary catalog file holding only IDs, text
+// and help; see catalog.Localize.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"gopkg.in/yaml.v3"
+)
+
+// Fields of an entry that are translated
+const (
+	FieldText = "text"
+	FieldHelp = "help"
+)
+
+// Unit is one translatable string
+type Unit struct {
+	// ID is the message ID and Field is FieldText or FieldHelp
+	ID    string
+	Field string
+	// Source is the wording in the base catalog
+	Source string
+	// Target is the translation, empty when there is none
+	Target string
+	// Note is context for the translator
+	Note string
+}
+
+// Key identifies the unit in translation files: "SRV002.text"
+func (u Unit) Key() string {
+	return u.ID + "." + u.Field
+}
+
+// File is a translation file read back
+type File struct {
+	// SourceLanguage and Language are the language tags in the file, if any
+	SourceLanguage string
+	Language       string
+	Units          []Unit
+}
+
+// codePattern matches the parts of a string translators must not change
+var codePattern = regexp.MustCompile(`\{[^{}]*\}|\b(?:Cause|Recovery):`)
+
+// Codes returns the protected parts of s in order
+func Codes(s string) []string {
+	return codePattern.FindAllString(s, -1)
+}
+
+// segment is a run of text or a protected code
+type segment struct {
+	text string
+	code bool
+}
+
+func segments(s string) []segment {
+	var segs []segment
+	last := 0
+	for _, loc := range codePattern.FindAllStringIndex(s, -1) {
+		if loc[0] > last {
+			segs = append(segs, segment{text: s[last:loc[0]]})
+		}
+		segs = append(segs, segment{text: s[loc[0]:loc[1]], code: true})
+		last = loc[1]
+	}
+	if last < len(s) {
+		segs = append(segs, segment{text: s[last:]})
+	}
+	return segs
+}
+
+// sameCodes reports whether a and b hold the same protected parts, in
+// any order
+func sameCodes(a, b string) bool {
+	ca, cb := Codes(a), Codes(b)
+	if len(ca) != len(cb) {
+		return false
+	}
+	sort.Strings(ca)
+	sort.Strings(cb)
+	for i := range ca {
+		if ca[i] != cb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Units lists the strings of base to translate, ordered by ID, with the
+// translations already in translation (which may be nil) as targets
+func Units(base, translation catalog.Catalog) []Unit {
+	ids := make([]string, 0, len(base))
+	for id := range base {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var units []Unit
+	for _, id := range ids {
+		e, t := base[id], translation[id]
+		note := e.Severity + " message"
+		if codes := Codes(e.Text); len(codes) > 0 {
+			note += "; keep " + strings.Join(codes, " ") + " unchanged"
+		}
+		units = append(units, Unit{ID: id, Field: FieldText, Source: e.Text, Target: t.Text, Note: note})
+		if e.Help != "" {
+			note := "Help for " + id
+			if codes := Codes(e.Help); len(codes) > 0 {
+				note += "; keep " + strings.Join(codes, " ") + " unchanged"
+			}
+			units = append(units, Unit{ID: id, Field: FieldHelp, Source: e.Help, Target: t.Help, Note: note})
+		}
+	}
+	return units
+}
+
+// Apply merges the translated units into a copy of translation, which
+// may be nil. Units for unknown IDs, units whose source no longer matches
+// base and translations that change protected parts are skipped and
+// reported.
+func Apply(base, translation catalog.Catalog, units []Unit) (catalog.Catalog, []catalog.Problem) {
+	merged := make(catalog.Catalog, len(translation))
+	for id, e := range translation {
+		merged[id] = catalog.CatalogEntry{ID: id, Text: e.Text, Help: e.Help}
+	}
+
+	var problems []catalog.Problem
+	report := func(i int, u Unit, warning bool, format string, args ...interface{}) {
+		problems = append(problems, catalog.Problem{
+			Index:   i,
+			ID:      u.Key(),
+			Message: fmt.Sprintf(format, args...),
+			Warning: warning,
+		})
+	}
+	for i, u := range units {
+		if u.Target == "" {
+			continue
+		}
+		e, ok := base[u.ID]
+		if !ok {
+			report(i, u, true, "unknown message ID; skipped")
+			continue
+		}
+		var source string
+		switch u.Field {
+		case FieldText:
+			source = e.Text
+		case FieldHelp:
+			source = e.Help
+		default:
+			report(i, u, true, "unknown field %q; skipped", u.Field)
+			continue
+		}
+		if u.Source != "" && u.Source != source {
+			report(i, u, true, "source changed since export; skipped")
+			continue
+		}
+		if !sameCodes(source, u.Target) {
+			report(i, u, false, "translation changes protected parts %v; skipped", Codes(source))
+			continue
+		}
+
+		t := merged[u.ID]
+		t.ID = u.ID
+		if u.Field == FieldText {
+			t.Text = u.Target
+		} else {
+			t.Help = u.Target
+		}
+		merged[u.ID] = t
+	}
+	return merged, problems
+}
+
+// Report says how complete a locale catalog is
+type Report struct {
+	Locale string
+	// Total and Translated count strings: the text and help of entries
+	Total, Translated int
+	// Missing lists the keys without a translation
+	Missing []string
+	// Invalid lists the keys whose translation changes protected parts
+	Invalid []string
+	// Obsolete lists IDs that are no longer in the base catalog
+	Obsolete []string
+}
+
+// Percent returns the share of translated strings
+func (r Report) Percent() float64 {
+	if r.Total == 0 {
+		return 100
+	}
+	return 100 * float64(r.Translated) / float64(r.Total)
+}
+
+// Check compares a locale catalog with its base catalog
+func Check(locale string, base, translation catalog.Catalog) Report {
+	r := Report{Locale: locale}
+	for _, u := range Units(base, translation) {
+		r.Total++
+		switch {
+		case u.Target == "":
+			r.Missing = append(r.Missing, u.Key())
+		case !sameCodes(u.Source, u.Target):
+			r.Invalid = append(r.Invalid, u.Key())
+		default:
+			r.Translated++
+		}
+	}
+	for id := range translation {
+		if _, ok := base[id]; !ok {
+			r.Obsolete = append(r.Obsolete, id)
+		}
+	}
+	sort.Strings(r.Obsolete)
+	return r
+}
+
+// localeEntry is an entry of a locale catalog file
+type localeEntry struct {
+	ID   string `yaml:"id" json:"id"`
+	Text string `yaml:"text,omitempty" json:"text,omitempty"`
+	Help string `yaml:"help,omitempty" json:"help,omitempty"`
+}
+
+// WriteCatalog writes translation as a locale catalog file in format,
+// one of the catalog.Format constants, ordered by ID
+func WriteCatalog(w io.Writer, translation catalog.Catalog, format string) error {
+	entries := make([]localeEntry, 0, len(translation))
+	for id, e := range translation {
+		if e.Text == "" && e.Help == "" {
+			continue
+		}
+		entries = append(entries, localeEntry{ID: id, Text: e.Text, Help: e.Help})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+
+	switch format {
+	case catalog.FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(entries); err != nil {
+			return err
+		}
+		return enc.Close()
+	case catalog.FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	case catalog.FormatJSONLines:
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("i18n: cannot write %s catalogs", format)
+}
+
+// splitKey splits "SRV002.text" into ID and field
+func splitKey(key string) (string, string, bool) {
+	i := strings.LastIndex(key, ".")
+	if i <= 0 {
+		return "", "", false
+	}
+	return key[:i], key[i+1:], true
+}
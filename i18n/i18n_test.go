@@ -0,0 +1,119 @@
+package i18n
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+func TestWriteCatalog(t *testing.T) {
+	translation := catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Text: "Port {port} konnte nicht gebunden werden", Help: "Ursache: Port belegt."},
+		{ID: "SRV001", Text: "Server startet auf Port {port}"},
+		{ID: "SRV003"}, // untranslated
+	})
+	for _, format := range []string{catalog.FormatYAML, catalog.FormatJSON, catalog.FormatJSONLines} {
+		var buf bytes.Buffer
+		if err := WriteCatalog(&buf, translation, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		entries, err := catalog.ReadEntries(bytes.NewReader(buf.Bytes()), format)
+		if err != nil {
+			t.Fatalf("%s: %v\n%s", format, err, buf.Bytes())
+		}
+		if len(entries) != 2 || entries[0].ID != "SRV001" || entries[1].ID != "SRV002" {
+			t.Fatalf("%s: read back %+v", format, entries)
+		}
+		if entries[1].Text != translation["SRV002"].Text || entries[1].Help != translation["SRV002"].Help {
+			t.Errorf("%s: SRV002 = %+v", format, entries[1])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteCatalog(&buf, translation, "toml"); err == nil || !strings.Contains(err.Error(), "toml") {
+		t.Errorf("unknown format: %v", err)
+	}
+}
+
+// testUnits are the units of a small catalog with German translations
+func testUnits() (catalog.Catalog, []Unit) {
+	base := catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Severity: "ERROR", Text: "Failed to bind to port {port} on {host}", Help: "Cause: Port in use. Recovery: Free it."},
+		{ID: "SRV001", Severity: "INFO", Text: `Server "main" starting` + "\n\ton a <new> & shiny port"},
+		{ID: "DB001", Severity: "WARN", Text: "Slow query", Help: "Cause: No index."},
+	})
+	translation := catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Text: "Port {port} auf {host} nicht verfügbar", Help: "Cause: Port belegt. Recovery: Freigeben."},
+		{ID: "SRV001", Text: `Server "main" startet` + "\n\tauf <neuem> & glänzendem Port"},
+	})
+	return base, Units(base, translation)
+}
+
+func TestUnits(t *testing.T) {
+	_, units := testUnits()
+	var keys []string
+	for _, u := range units {
+		keys = append(keys, u.Key())
+	}
+	if got := strings.Join(keys, " "); got != "DB001.text DB001.help SRV001.text SRV002.text SRV002.help" {
+		t.Errorf("keys %s", got)
+	}
+	if u := units[3]; u.Note != "ERROR message; keep {port} {host} unchanged" || u.Target != "Port {port} auf {host} nicht verfügbar" {
+		t.Errorf("SRV002.text = %+v", u)
+	}
+	if u := units[4]; u.Note != "Help for SRV002; keep Cause: Recovery: unchanged" {
+		t.Errorf("SRV002.help note %q", u.Note)
+	}
+}
+
+func TestApply(t *testing.T) {
+	base, _ := testUnits()
+	existing := catalog.FromEntries([]catalog.CatalogEntry{{ID: "DB001", Text: "Langsame Abfrage"}})
+	units := []Unit{
+		{ID: "SRV002", Field: FieldText, Source: base["SRV002"].Text, Target: "Port {host} auf {port} belegt"}, // reordered: fine
+		{ID: "SRV002", Field: FieldHelp, Source: base["SRV002"].Help, Target: "Ursache: Port belegt. Recovery: Freigeben."},
+		{ID: "SRV001", Field: FieldText, Target: "Server startet auf {port}"},
+		{ID: "DB001", Field: FieldHelp, Source: "Cause: Old help.", Target: "Cause: Alte Hilfe."},
+		{ID: "DB001", Field: FieldText, Source: "Slow query", Target: ""},
+		{ID: "NOPE001", Field: FieldText, Target: "Unbekannt"},
+		{ID: "DB001", Field: "title", Target: "Titel"},
+		{ID: "DB001", Field: FieldHelp, Target: "Cause: Kein Index."},
+	}
+	merged, problems := Apply(base, existing, units)
+
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"SRV002.help: error: translation changes protected parts [Cause: Recovery:]; skipped",
+		"SRV001.text: error: translation changes protected parts []; skipped",
+		"DB001.help: warning: source changed since export; skipped",
+		"NOPE001.text: warning: unknown message ID; skipped",
+		"DB001.title: warning: unknown field \"title\"; skipped",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if problems[0].Index != 1 || problems[0].Warning {
+		t.Errorf("first problem %+v", problems[0])
+	}
+
+	wantMerged := catalog.FromEntries([]catalog.CatalogEntry{
+		{ID: "SRV002", Text: "Port {host} auf {port} belegt"},
+		{ID: "DB001", Text: "Langsame Abfrage", Help: "Cause: Kein Index."},
+	})
+	if len(merged) != len(wantMerged) {
+		t.Fatalf("merged %+v", merged)
+	}
+	for id, e := range wantMerged {
+		if m := merged[id]; m.ID != e.ID || m.Text != e.Text || m.Help != e.Help || m.Severity != "" {
+			t.Errorf("%s = %+v, want %+v", id, m, e)
+		}
+	}
+	if existing["DB001"].Help != "" {
+		t.Error("Apply changed the translation it was given")
+	}
+}
@@ -0,0 +1,165 @@
+package i18n
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WritePO writes units as a gettext PO file for language. The unit key is
+// the msgctxt, so identical wording in different messages can be
+// translated differently.
+func WritePO(w io.Writer, units []Unit, language string) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "# Translations of the opsmsg message catalog")
+	fmt.Fprintln(b, `msgid ""`)
+	fmt.Fprintln(b, `msgstr ""`)
+	fmt.Fprintln(b, `"Content-Type: text/plain; charset=UTF-8\n"`)
+	fmt.Fprintln(b, `"Content-Transfer-Encoding: 8bit\n"`)
+	if language != "" {
+		fmt.Fprintf(b, "\"Language: %s\\n\"\n", poEscape(language))
+	}
+	fmt.Fprintln(b, `"X-Generator: opsmsg\n"`)
+
+	for _, u := range units {
+		fmt.Fprintln(b)
+		if u.Note != "" {
+			fmt.Fprintf(b, "#. %s\n", u.Note)
+		}
+		if strings.Contains(u.Source, "{") {
+			fmt.Fprintln(b, "#, python-brace-format")
+		}
+		fmt.Fprintf(b, "msgctxt %s\n", poQuote(u.Key()))
+		fmt.Fprintf(b, "msgid %s\n", poQuote(u.Source))
+		fmt.Fprintf(b, "msgstr %s\n", poQuote(u.Target))
+	}
+	return b.Flush()
+}
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
+func poEscape(s string) string {
+	return poEscaper.Replace(s)
+}
+
+func poQuote(s string) string {
+	return `"` + poEscape(s) + `"`
+}
+
+// ReadPO reads the translations from a PO file. Fuzzy entries, plural
+// forms and entries without a "ID.field" msgctxt are left out.
+func ReadPO(r io.Reader) (File, error) {
+	var (
+		f       File
+		entry   poEntry
+		current *string // string that continuation lines append to
+		line    int
+	)
+	flush := func() {
+		if entry.started {
+			f.add(entry)
+		}
+		entry, current = poEntry{}, nil
+	}
+
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	for lines.Scan() {
+		line++
+		text := strings.TrimSpace(lines.Text())
+		switch {
+		case text == "":
+			flush()
+		case strings.HasPrefix(text, "#,"):
+			if entry.started && entry.msgstr != nil {
+				flush()
+			}
+			entry.fuzzy = entry.fuzzy || strings.Contains(text, "fuzzy")
+		case strings.HasPrefix(text, "#"):
+			// Comments, including obsolete #~ entries
+		case strings.HasPrefix(text, `"`):
+			if current == nil {
+				return File{}, fmt.Errorf("line %d: string without keyword", line)
+			}
+			s, err := poUnquote(text)
+			if err != nil {
+				return File{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			*current += s
+		default:
+			keyword, rest, _ := strings.Cut(text, " ")
+			s, err := poUnquote(strings.TrimSpace(rest))
+			if err != nil {
+				return File{}, fmt.Errorf("line %d: %v", line, err)
+			}
+			// A new msgctxt or msgid after msgstr starts the next entry
+			if (keyword == "msgctxt" || keyword == "msgid") && entry.msgstr != nil {
+				flush()
+			}
+			entry.started = true
+			switch {
+			case keyword == "msgctxt":
+				entry.msgctxt = s
+				current = &entry.msgctxt
+			case keyword == "msgid":
+				entry.msgid = s
+				current = &entry.msgid
+			case keyword == "msgstr" || keyword == "msgstr[0]":
+				entry.msgstr = new(string)
+				*entry.msgstr = s
+				current = entry.msgstr
+				entry.plural = entry.plural || keyword != "msgstr"
+			case keyword == "msgid_plural" || strings.HasPrefix(keyword, "msgstr["):
+				entry.plural = true
+				current = new(string)
+			default:
+				return File{}, fmt.Errorf("line %d: unknown keyword %q", line, keyword)
+			}
+		}
+	}
+	flush()
+	return f, lines.Err()
+}
+
+type poEntry struct {
+	started        bool
+	msgctxt, msgid string
+	msgstr         *string
+	fuzzy, plural  bool
+}
+
+func (f *File) add(e poEntry) {
+	if e.msgid == "" && e.msgctxt == "" {
+		// The header
+		for _, field := range strings.Split(derefString(e.msgstr), "\n") {
+			if v, ok := strings.CutPrefix(field, "Language:"); ok {
+				f.Language = strings.TrimSpace(v)
+			}
+		}
+		return
+	}
+	id, field, ok := splitKey(e.msgctxt)
+	if !ok || e.fuzzy || e.plural {
+		return
+	}
+	f.Units = append(f.Units, Unit{ID: id, Field: field, Source: e.msgid, Target: derefString(e.msgstr)})
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// poUnquote decodes a quoted PO string
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("want a quoted string, got %q", s)
+	}
+	// PO escapes are a subset of Go's, except that a bare quote cannot
+	// appear inside
+	return strconv.Unquote(s)
+}
@@ -0,0 +1,97 @@
+package i18n
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// targets returns the units as read back from a file: without notes
+func targets(units []Unit) []Unit {
+	out := make([]Unit, len(units))
+	for i, u := range units {
+		u.Note = ""
+		out[i] = u
+	}
+	return out
+}
+
+func TestPORoundTrip(t *testing.T) {
+	_, units := testUnits()
+	var buf bytes.Buffer
+	if err := WritePO(&buf, units, "de"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"\"Language: de\\n\"\n",
+		"#. ERROR message; keep {port} {host} unchanged\n#, python-brace-format\nmsgctxt \"SRV002.text\"\n",
+		`msgid "Server \"main\" starting\n\ton a <new> & shiny port"`,
+		"msgctxt \"DB001.help\"\nmsgid \"Cause: No index.\"\nmsgstr \"\"\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("PO lacks %q:\n%s", want, out)
+		}
+	}
+
+	f, err := ReadPO(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Language != "de" || !reflect.DeepEqual(f.Units, targets(units)) {
+		t.Errorf("read back %s %+v\nwant %+v", f.Language, f.Units, targets(units))
+	}
+}
+
+func TestReadPO(t *testing.T) {
+	const po = `# Translator comment
+msgid ""
+msgstr ""
+"Language: fr\n"
+
+#, fuzzy, python-brace-format
+msgctxt "SRV002.text"
+msgid "Failed to bind to port {port}"
+msgstr "Port {port} indisponible"
+
+msgctxt "SRV001.text"
+msgid ""
+"Server "
+"starting"
+msgstr ""
+"Démarrage "
+"du serveur"
+msgctxt "SRV003.text"
+msgid "one"
+msgid_plural "many"
+msgstr[0] "un"
+msgstr[1] "plusieurs"
+
+msgid "no context"
+msgstr "sans contexte"
+
+#~ msgctxt "OLD001.text"
+#~ msgid "Old"
+#~ msgstr "Vieux"
+`
+	f, err := ReadPO(strings.NewReader(po))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{{ID: "SRV001", Field: FieldText, Source: "Server starting", Target: "Démarrage du serveur"}}
+	if f.Language != "fr" || !reflect.DeepEqual(f.Units, want) {
+		t.Errorf("read %s %+v, want %+v", f.Language, f.Units, want)
+	}
+
+	for _, bad := range []string{
+		"\"orphan\"\n",
+		"msgid unquoted\n",
+		"msgid \"a\"\nmsgstr \"b\nline\"\n",
+		"msgid \"a\"\nmsgcomment \"b\"\n",
+	} {
+		if _, err := ReadPO(strings.NewReader(bad)); err == nil || !strings.HasPrefix(err.Error(), "line ") {
+			t.Errorf("ReadPO(%q) = %v, want a line error", bad, err)
+		}
+	}
+}
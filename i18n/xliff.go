@@ -0,0 +1,272 @@
+package i18n
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteXLIFF12 writes units as an XLIFF 1.2 file. Protected parts become
+// <ph> inline codes holding the original text.
+func WriteXLIFF12(w io.Writer, units []Unit, sourceLanguage, targetLanguage string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	b.WriteString(`<xliff version="1.2" xmlns="urn:oasis:names:tc:xliff:document:1.2">` + "\n")
+	fmt.Fprintf(b, `  <file original="opsmsg-catalog" datatype="plaintext" source-language="%s"`, escape(sourceLanguage))
+	if targetLanguage != "" {
+		fmt.Fprintf(b, ` target-language="%s"`, escape(targetLanguage))
+	}
+	b.WriteString(">\n    <body>\n")
+	for _, u := range units {
+		fmt.Fprintf(b, "      <trans-unit id=\"%s\">\n", escape(u.Key()))
+		codes := newCodeNumbers()
+		b.WriteString("        <source>")
+		writeCodes12(b, u.Source, codes.source)
+		b.WriteString("</source>\n")
+		if u.Target != "" {
+			b.WriteString(`        <target state="translated">`)
+			writeCodes12(b, u.Target, codes.target)
+			b.WriteString("</target>\n")
+		}
+		if u.Note != "" {
+			b.WriteString("        <note>" + escape(u.Note) + "</note>\n")
+		}
+		b.WriteString("      </trans-unit>\n")
+	}
+	b.WriteString("    </body>\n  </file>\n</xliff>\n")
+	return b.Flush()
+}
+
+func writeCodes12(b *bufio.Writer, s string, number func(code string) int) {
+	for _, seg := range segments(s) {
+		if seg.code {
+			fmt.Fprintf(b, `<ph id="%d">%s</ph>`, number(seg.text), escape(seg.text))
+		} else {
+			b.WriteString(escape(seg.text))
+		}
+	}
+}
+
+// WriteXLIFF20 writes units as an XLIFF 2.0 file. Protected parts become
+// <ph> inline codes whose original text is kept in <originalData>.
+func WriteXLIFF20(w io.Writer, units []Unit, sourceLanguage, targetLanguage string) error {
+	b := bufio.NewWriter(w)
+	b.WriteString(xml.Header)
+	fmt.Fprintf(b, `<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="2.0" srcLang="%s"`, escape(sourceLanguage))
+	if targetLanguage != "" {
+		fmt.Fprintf(b, ` trgLang="%s"`, escape(targetLanguage))
+	}
+	b.WriteString(">\n")
+	b.WriteString(`  <file id="f1" original="opsmsg-catalog">` + "\n")
+	for _, u := range units {
+		fmt.Fprintf(b, "    <unit id=\"%s\">\n", escape(u.Key()))
+		if u.Note != "" {
+			b.WriteString("      <notes><note>" + escape(u.Note) + "</note></notes>\n")
+		}
+
+		codes := newCodeNumbers()
+		source := inline20(u.Source, codes.source)
+		target := inline20(u.Target, codes.target)
+		if len(codes.data) > 0 {
+			b.WriteString("      <originalData>")
+			for i, d := range codes.data {
+				fmt.Fprintf(b, `<data id="d%d">%s</data>`, i+1, escape(d))
+			}
+			b.WriteString("</originalData>\n")
+		}
+		state := "initial"
+		if u.Target != "" {
+			state = "translated"
+		}
+		fmt.Fprintf(b, "      <segment state=\"%s\">\n", state)
+		b.WriteString("        <source>" + source + "</source>\n")
+		if u.Target != "" {
+			b.WriteString("        <target>" + target + "</target>\n")
+		}
+		b.WriteString("      </segment>\n    </unit>\n")
+	}
+	b.WriteString("  </file>\n</xliff>\n")
+	return b.Flush()
+}
+
+// codeNumbers numbers the protected parts of a unit. Source codes are
+// numbered in order; a target code takes the number of a source code
+// with the same text, so tools can pair them up.
+type codeNumbers struct {
+	data []string
+	free map[string][]int
+}
+
+func newCodeNumbers() *codeNumbers {
+	return &codeNumbers{free: make(map[string][]int)}
+}
+
+func (c *codeNumbers) source(code string) int {
+	c.data = append(c.data, code)
+	n := len(c.data)
+	c.free[code] = append(c.free[code], n)
+	return n
+}
+
+func (c *codeNumbers) target(code string) int {
+	if free := c.free[code]; len(free) > 0 {
+		c.free[code] = free[1:]
+		return free[0]
+	}
+	c.data = append(c.data, code)
+	return len(c.data)
+}
+
+// inline20 renders s with its protected parts as <ph> elements that
+// refer to originalData by number
+func inline20(s string, number func(code string) int) string {
+	var b strings.Builder
+	for _, seg := range segments(s) {
+		if seg.code {
+			n := number(seg.text)
+			fmt.Fprintf(&b, `<ph id="ph%d" dataRef="d%d" disp="%s"/>`, n, n, escape(seg.text))
+		} else {
+			b.WriteString(escape(seg.text))
+		}
+	}
+	return b.String()
+}
+
+// escape escapes s for XML text and attribute values
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// ReadXLIFF reads the translations from an XLIFF 1.2 or 2.0 file. Inline
+// codes are turned back into their original text; units whose ID is not
+// "ID.field" are left out.
+func ReadXLIFF(r io.Reader) (File, error) {
+	var f File
+	dec := xml.NewDecoder(r)
+
+	var (
+		key      string
+		data     map[string]string // XLIFF 2.0 originalData
+		dataID   string
+		in       *strings.Builder // source or target being read
+		source   strings.Builder
+		target   strings.Builder
+		skip     int // depth inside an element whose text is not content
+		inData   bool
+		hasUnits bool
+	)
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return File{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "xliff":
+				if v := attrValue(t, "srcLang"); v != "" {
+					f.SourceLanguage = v
+				}
+				if v := attrValue(t, "trgLang"); v != "" {
+					f.Language = v
+				}
+			case "file":
+				if v := attrValue(t, "source-language"); v != "" {
+					f.SourceLanguage = v
+				}
+				if v := attrValue(t, "target-language"); v != "" {
+					f.Language = v
+				}
+			case "trans-unit", "unit":
+				key, data = attrValue(t, "id"), make(map[string]string)
+				source.Reset()
+				target.Reset()
+				hasUnits = true
+			case "data":
+				dataID, inData = attrValue(t, "id"), true
+			case "source":
+				if in == nil {
+					in = &source
+				}
+			case "target":
+				if in == nil {
+					in = &target
+				}
+			case "ph", "x", "bx", "ex", "sc", "ec":
+				if in == nil {
+					continue
+				}
+				// 2.0 refers to originalData, 1.2 <x> carries equiv-text
+				// and 1.2 <ph> holds the original text as content
+				if ref := attrValue(t, "dataRef"); ref != "" {
+					in.WriteString(data[ref])
+					skip = 1
+				} else if v := attrValue(t, "equiv-text"); v != "" {
+					in.WriteString(v)
+					skip = 1
+				} else if v := attrValue(t, "equiv"); v != "" && t.Name.Local != "ph" {
+					in.WriteString(v)
+					skip = 1
+				}
+			case "note", "notes", "alt-trans", "seg-source":
+				skip = 1
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "data":
+				inData = false
+			case "source":
+				if in == &source {
+					in = nil
+				}
+			case "target":
+				if in == &target {
+					in = nil
+				}
+			case "trans-unit", "unit":
+				if id, field, ok := splitKey(key); ok {
+					f.Units = append(f.Units, Unit{ID: id, Field: field, Source: source.String(), Target: target.String()})
+				}
+				key = ""
+			}
+		case xml.CharData:
+			switch {
+			case skip > 0:
+			case inData:
+				data[dataID] += string(t)
+			case in != nil:
+				in.Write(t)
+			}
+		}
+	}
+	if !hasUnits {
+		return File{}, errors.New("no translation units found; is this an XLIFF file?")
+	}
+	return f, nil
+}
+
+func attrValue(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package i18n
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXLIFFRoundTrip(t *testing.T) {
+	_, units := testUnits()
+	for _, tc := range []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		want  []string
+	}{
+		{"1.2", func(b *bytes.Buffer) error { return WriteXLIFF12(b, units, "en", "de") }, []string{
+			`<file original="opsmsg-catalog" datatype="plaintext" source-language="en" target-language="de">`,
+			`<source>Failed to bind to port <ph id="1">{port}</ph> on <ph id="2">{host}</ph></source>`,
+			`<target state="translated">Port <ph id="1">{port}</ph> auf <ph id="2">{host}</ph> nicht verfügbar</target>`,
+			`&lt;new&gt; &amp; shiny`,
+		}},
+		{"2.0", func(b *bytes.Buffer) error { return WriteXLIFF20(b, units, "en", "de") }, []string{
+			`version="2.0" srcLang="en" trgLang="de"`,
+			`<unit id="SRV002.text">`,
+			`<data id="d1">{port}</data>`,
+		}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tc.write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tc.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("lacks %s:\n%s", want, buf.String())
+				}
+			}
+			f, err := ReadXLIFF(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.SourceLanguage != "en" || f.Language != "de" {
+				t.Errorf("languages %q, %q", f.SourceLanguage, f.Language)
+			}
+			if !reflect.DeepEqual(f.Units, targets(units)) {
+				t.Errorf("read back %+v\nwant %+v", f.Units, targets(units))
+			}
+		})
+	}
+}
+
+func TestReadXLIFF(t *testing.T) {
+	// Inline codes as other tools write them
+	const doc = `<?xml version="1.0"?>
+<xliff version="1.2"><file source-language="en" target-language="fr"><body>
+<trans-unit id="SRV002.text">
+  <source>Port <x id="1" equiv-text="{port}"/> busy</source>
+  <seg-source>ignored</seg-source>
+  <target>Port <x id="1" equiv-text="{port}"/> occupé</target>
+  <alt-trans><target>ignored</target></alt-trans>
+  <note>ignored</note>
+</trans-unit>
+<trans-unit id="no-field"><source>a</source></trans-unit>
+</body></file></xliff>`
+	f, err := ReadXLIFF(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Unit{{ID: "SRV002", Field: FieldText, Source: "Port {port} busy", Target: "Port {port} occupé"}}
+	if f.Language != "fr" || !reflect.DeepEqual(f.Units, want) {
+		t.Errorf("read %s %+v, want %+v", f.Language, f.Units, want)
+	}
+
+	for _, bad := range []string{"<xliff><file></file></xliff>", "<html><body>", "not xml"} {
+		if _, err := ReadXLIFF(strings.NewReader(bad)); err == nil {
+			t.Errorf("ReadXLIFF(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package message
+
+import "strings"
+
+// IDStyle describes the classic mainframe way of writing message IDs: a
+// severity letter follows the ID (SRV002E) and an optional component
+// prefix precedes it (OPSSRV002E).
+type IDStyle struct {
+	// Prefix is prepended to every ID, e.g. a component code
+	Prefix string
+	// Suffixes maps each severity to the letter appended to the ID
+	Suffixes map[Severity]string
+}
+
+// MainframeIDs is the traditional convention: Informational, Warning,
+// Error and Severe
+var MainframeIDs = IDStyle{
+	Suffixes: map[Severity]string{
+		Info:     "I",
+		Warn:     "W",
+		Error:    "E",
+		Critical: "S",
+	},
+}
+
+// Format returns id in the compact form for the given severity
+func (s IDStyle) Format(id string, sev Severity) string {
+	return s.Prefix + id + s.Suffixes[sev]
+}
+
+// Parse splits a compact ID into the plain ID and the severity its suffix
+// stands for. ok is false when ref carries neither the prefix nor a known
+// suffix.
+func (s IDStyle) Parse(ref string) (id string, sev Severity, ok bool) {
+	id = ref
+	if s.Prefix != "" && strings.HasPrefix(id, s.Prefix) {
+		id = strings.TrimPrefix(id, s.Prefix)
+		ok = true
+	}
+	// Prefer the longest matching suffix
+	best := ""
+	for severity, suffix := range s.Suffixes {
+		if suffix != "" && len(suffix) > len(best) && len(suffix) < len(id) && strings.HasSuffix(id, suffix) {
+			best, sev = suffix, severity
+		}
+	}
+	if best != "" {
+		return strings.TrimSuffix(id, best), sev, true
+	}
+	return id, "", ok
+}
@@ -1,6 +1,10 @@
 package message
 
-import "time"
+import (
+    "sort"
+    "strings"
+    "time"
+)
 
 type Severity string
 
@@ -11,6 +15,23 @@ const (
     Critical Severity = "CRITICAL"
 )
 
+// Rank orders severities from least to most severe. Unknown severities
+// rank below Info.
+func (s Severity) Rank() int {
+    switch s {
+    case Info:
+        return 1
+    case Warn:
+        return 2
+    case Error:
+        return 3
+    case Critical:
+        return 4
+    default:
+        return 0
+    }
+}
+
 type Message struct {
     ID        string
     Severity  Severity
@@ -19,4 +40,70 @@ type Message struct {
     Timestamp time.Time
     Help      string
     Replies   []string
+    // Links are runbook and documentation URLs
+    Links     []string
+}
+
+// Render returns Text with {placeholder} tokens replaced by the matching
+// Context values. Placeholders without a value are left as they are.
+func (m Message) Render() string {
+    if len(m.Context) == 0 {
+        return m.Text
+    }
+    pairs := make([]string, 0, len(m.Context)*2)
+    for k, v := range m.Context {
+        pairs = append(pairs, "{"+k+"}", v)
+    }
+    return strings.NewReplacer(pairs...).Replace(m.Text)
+}
+
+// Placeholders returns the names of the {placeholder} tokens in text in
+// order of first appearance.
+func Placeholders(text string) []string {
+    var names []string
+    seen := make(map[string]bool)
+    for {
+        start := strings.IndexByte(text, '{')
+        if start < 0 {
+            break
+        }
+        end := strings.IndexByte(text[start+1:], '}')
+        if end < 0 {
+            break
+        }
+        name := text[start+1 : start+1+end]
+        if strings.ContainsRune(name, '{') {
+            // Resume at the inner brace, e.g. "{a{b}"
+            text = text[start+1:]
+            continue
+        }
+        if name != "" && !strings.ContainsAny(name, " \t\n") && !seen[name] {
+            seen[name] = true
+            names = append(names, name)
+        }
+        text = text[start+1+end+1:]
+    }
+    return names
+}
+
+// ContextKeys returns the keys of Context in display order: those whose
+// placeholders appear in Text first, in order of appearance, then the
+// rest alphabetically.
+func (m Message) ContextKeys() []string {
+    keys := make([]string, 0, len(m.Context))
+    seen := make(map[string]bool, len(m.Context))
+    for _, k := range Placeholders(m.Text) {
+        if _, ok := m.Context[k]; ok {
+            seen[k] = true
+            keys = append(keys, k)
+        }
+    }
+    rest := make([]string, 0, len(m.Context)-len(keys))
+    for k := range m.Context {
+        if !seen[k] {
+            rest = append(rest, k)
+        }
+    }
+    sort.Strings(rest)
+    return append(keys, rest...)
 }
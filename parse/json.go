@@ -0,0 +1,169 @@
+package parse
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// ErrUnknownFormat is returned for JSON records that are not ECS, GELF or
+// logrus JSON
+var ErrUnknownFormat = errors.New("parse: unknown JSON record format")
+
+// ParseJSON reads one record written by encode.ECS, encode.GELF or the
+// logrus JSONFormatter with the LogrusDispatcher fields
+func ParseJSON(data []byte) (message.Message, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return message.Message{}, err
+	}
+	if ops, ok := doc["opsmsg"].(map[string]interface{}); ok {
+		return ecsMessage(doc, ops), nil
+	}
+	if _, ok := doc["short_message"]; ok && doc["version"] == "1.1" {
+		return gelfMessage(doc), nil
+	}
+	if _, ok := doc["msg"]; ok {
+		if _, ok := doc["level"]; ok {
+			return logrusMessage(doc), nil
+		}
+	}
+	return message.Message{}, ErrUnknownFormat
+}
+
+func ecsMessage(doc, ops map[string]interface{}) message.Message {
+	msg := message.Message{
+		ID:       str(ops["id"]),
+		Severity: message.Severity(str(ops["severity"])),
+		Text:     str(ops["template"]),
+		Help:     str(ops["help"]),
+		Replies:  list(ops["replies"]),
+		Links:    list(ops["links"]),
+		Context:  make(map[string]string),
+	}
+	if msg.Text == "" {
+		msg.Text = str(doc["message"])
+	}
+	msg.Timestamp, _ = time.Parse(time.RFC3339Nano, str(doc["@timestamp"]))
+	if labels, ok := doc["labels"].(map[string]interface{}); ok {
+		for k, v := range labels {
+			msg.Context[k] = str(v)
+		}
+	}
+	return msg
+}
+
+// gelfInternal lists the additional fields encode.GELF sets itself
+var gelfInternal = map[string]bool{
+	"_message_id": true, "_severity": true, "_template": true,
+	"_help": true, "_replies": true, "_links": true,
+}
+
+func gelfMessage(doc map[string]interface{}) message.Message {
+	msg := message.Message{
+		ID:       str(doc["_message_id"]),
+		Severity: message.Severity(str(doc["_severity"])),
+		Text:     str(doc["_template"]),
+		Help:     str(doc["_help"]),
+		Replies:  lines(str(doc["_replies"])),
+		Links:    lines(str(doc["_links"])),
+		Context:  make(map[string]string),
+	}
+	if msg.Text == "" {
+		msg.Text = str(doc["short_message"])
+	}
+	if ts, ok := doc["timestamp"].(float64); ok {
+		sec, frac := math.Modf(ts)
+		msg.Timestamp = time.Unix(int64(sec), int64(math.Round(frac*1e3))*1e6).UTC()
+	}
+	for k, v := range doc {
+		if !strings.HasPrefix(k, "_") || gelfInternal[k] {
+			continue
+		}
+		msg.Context[gelfContextKey(k)] = str(v)
+	}
+	return msg
+}
+
+// gelfContextKey returns the context key of additional field k.
+// encode.GELF writes keys that would collide with "_id" or its own fields
+// as "_ctx_<key>", and so keys that merely start with "ctx_" keep it.
+func gelfContextKey(k string) string {
+	key := strings.TrimPrefix(k, "_")
+	if rest, ok := strings.CutPrefix(key, "ctx_"); ok && gelfRenamed(rest) {
+		return rest
+	}
+	return key
+}
+
+// gelfRenamed reports whether encode.GELF writes context key name with
+// a "ctx_" prefix
+func gelfRenamed(name string) bool {
+	if rest, ok := strings.CutPrefix(name, "ctx_"); ok {
+		return gelfRenamed(rest)
+	}
+	return name == "id" || gelfInternal["_"+name]
+}
+
+// logrusInternal lists the keys logrus and the LogrusDispatcher set
+var logrusInternal = map[string]bool{
+	"msg": true, "level": true, "time": true, "id": true, "severity": true,
+	"help": true, "replies": true, "links": true,
+}
+
+func logrusMessage(doc map[string]interface{}) message.Message {
+	msg := message.Message{
+		ID:       str(doc["id"]),
+		Severity: message.Severity(str(doc["severity"])),
+		Text:     str(doc["msg"]),
+		Help:     str(doc["help"]),
+		Replies:  list(doc["replies"]),
+		Links:    list(doc["links"]),
+		Context:  make(map[string]string),
+	}
+	if msg.Severity == "" {
+		msg.Severity = message.Severity(strings.ToUpper(str(doc["level"])))
+	}
+	msg.Timestamp, _ = time.Parse(time.RFC3339Nano, str(doc["time"]))
+	for k, v := range doc {
+		if !logrusInternal[k] {
+			msg.Context[k] = str(v)
+		}
+	}
+	return msg
+}
+
+func str(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func list(v interface{}) []string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = str(item)
+	}
+	return out
+}
+
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,248 @@
+// Package parse reads messages back from log output written by the
+// formatters: the box, simple and classic layouts, and ECS, GELF or
+// logrus JSON. Color codes are ignored and wrapped lines are joined, so
+// existing log files can be post-processed by tools.
+//
+// The text layouts show the rendered text, so messages parsed from them
+// have placeholders already filled in. JSON records carry the template
+// and keep it.
+package parse
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Parser holds the settings the output was written with
+type Parser struct {
+	// TimestampFormat is the format of header timestamps (default: RFC3339)
+	TimestampFormat string
+	// IDStyle sets the severity letters of the classic layout (default:
+	// message.MainframeIDs)
+	IDStyle *message.IDStyle
+}
+
+// Scanner reads messages from formatted output one at a time. Lines that
+// are not part of a recognised record are skipped.
+type Scanner struct {
+	p       Parser
+	lines   *bufio.Scanner
+	pending *string
+	msg     message.Message
+}
+
+// NewScanner returns a Scanner reading from r with default settings
+func NewScanner(r io.Reader) *Scanner {
+	return Parser{}.NewScanner(r)
+}
+
+// NewScanner returns a Scanner reading from r
+func (p Parser) NewScanner(r io.Reader) *Scanner {
+	lines := bufio.NewScanner(r)
+	lines.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Scanner{p: p, lines: lines}
+}
+
+// All reads every message from r with default settings
+func All(r io.Reader) ([]message.Message, error) {
+	var msgs []message.Message
+	s := NewScanner(r)
+	for s.Scan() {
+		msgs = append(msgs, s.Message())
+	}
+	return msgs, s.Err()
+}
+
+// Scan advances to the next message, returning false at the end of the
+// input or on a read error
+func (s *Scanner) Scan() bool {
+	for {
+		line, ok := s.next()
+		if !ok {
+			return false
+		}
+		plain := strings.TrimSpace(StripANSI(line))
+		switch {
+		case strings.HasPrefix(plain, "╭"):
+			if msg, ok := s.box(plain); ok {
+				s.msg = msg
+				return true
+			}
+		case strings.HasPrefix(plain, "{"):
+			if msg, err := ParseJSON([]byte(plain)); err == nil {
+				s.msg = msg
+				return true
+			}
+		default:
+			if h, ok := s.p.header(plain); ok && h.simple {
+				s.msg = s.simple(h)
+				return true
+			}
+			if msg, ok := s.p.classic(plain); ok {
+				s.msg = msg
+				return true
+			}
+		}
+	}
+}
+
+// Message returns the message read by the last call to Scan
+func (s *Scanner) Message() message.Message {
+	return s.msg
+}
+
+// Err returns the first read error
+func (s *Scanner) Err() error {
+	return s.lines.Err()
+}
+
+func (s *Scanner) next() (string, bool) {
+	if s.pending != nil {
+		line := *s.pending
+		s.pending = nil
+		return line, true
+	}
+	if !s.lines.Scan() {
+		return "", false
+	}
+	return s.lines.Text(), true
+}
+
+// unread pushes line back so the next call to next returns it again
+func (s *Scanner) unread(line string) {
+	s.pending = &line
+}
+
+var (
+	csiPattern  = regexp.MustCompile("\x1b\\[[0-9;?]*[ -/]*[@-~]")
+	oscPattern  = regexp.MustCompile("\x1b\\][^\x07\x1b]*(?:\x07|\x1b\\\\)")
+	linkPattern = regexp.MustCompile("\x1b\\]8;[^;\x07\x1b]*;([^\x07\x1b]+)(?:\x07|\x1b\\\\)")
+
+	// "[timestamp] ID (SEVERITY)" optionally followed by ": text"
+	headerPattern = regexp.MustCompile(`^\[([^\]]*)\] (\S+) \(([^)]*)\)(: (.*))?$`)
+	replyPattern  = regexp.MustCompile(`^(\d+)\. (.*)$`)
+
+	// A classic ID, without its severity letter, ends with a digit
+	classicIDPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_.-]*[0-9]$`)
+	// A trailing " key=value" pair of a classic line
+	classicFieldPattern = regexp.MustCompile(` ([^\s="]+)=("(?:[^"\\]|\\.)*"|[^\s"]*)$`)
+)
+
+// StripANSI removes color codes and hyperlink markup from s
+func StripANSI(s string) string {
+	if !strings.Contains(s, "\x1b") {
+		return s
+	}
+	return csiPattern.ReplaceAllString(oscPattern.ReplaceAllString(s, ""), "")
+}
+
+// linkTarget returns the first OSC 8 hyperlink target in s
+func linkTarget(s string) string {
+	if m := linkPattern.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+type header struct {
+	msg    message.Message
+	simple bool
+}
+
+func (p Parser) header(line string) (header, bool) {
+	m := headerPattern.FindStringSubmatch(line)
+	if m == nil {
+		return header{}, false
+	}
+	ts, _ := time.Parse(p.timestampFormat(), m[1])
+	return header{
+		msg: message.Message{
+			ID:        m[2],
+			Severity:  message.Severity(m[3]),
+			Text:      m[5],
+			Timestamp: ts,
+			Context:   make(map[string]string),
+		},
+		simple: m[4] != "",
+	}, true
+}
+
+func (p Parser) timestampFormat() string {
+	if p.TimestampFormat == "" {
+		return time.RFC3339
+	}
+	return p.TimestampFormat
+}
+
+// classic reads a render.Classic line: "timestamp SRV002E text key=value".
+// Fields the text shows are not repeated, so only the others are read
+// back, from the key=value words at the end of the line; such words at the
+// end of the text itself are taken for fields too.
+func (p Parser) classic(line string) (message.Message, bool) {
+	// The timestamp may contain spaces, so try the prefixes up to each of
+	// the first few
+	var ts time.Time
+	rest := ""
+	for i, spaces := 0, 0; i < len(line) && spaces < 4; i++ {
+		if line[i] != ' ' {
+			continue
+		}
+		spaces++
+		if t, err := time.Parse(p.timestampFormat(), line[:i]); err == nil {
+			ts, rest = t, line[i+1:]
+			break
+		}
+	}
+	ref, text, _ := strings.Cut(rest, " ")
+	style := message.MainframeIDs
+	if p.IDStyle != nil {
+		style = *p.IDStyle
+	}
+	id, sev, ok := style.Parse(ref)
+	if rest == "" || !ok || sev == "" || !classicIDPattern.MatchString(id) {
+		return message.Message{}, false
+	}
+
+	msg := message.Message{ID: id, Severity: sev, Timestamp: ts, Context: make(map[string]string)}
+	for {
+		m := classicFieldPattern.FindStringSubmatchIndex(text)
+		if m == nil {
+			break
+		}
+		value := text[m[4]:m[5]]
+		if strings.HasPrefix(value, `"`) {
+			if v, err := strconv.Unquote(value); err == nil {
+				value = v
+			}
+		}
+		msg.Context[text[m[2]:m[3]]] = value
+		text = text[:m[0]]
+	}
+	msg.Text = text
+	return msg, true
+}
+
+// field splits "key=value"
+func field(s string) (string, string, bool) {
+	i := strings.Index(s, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+func indentOf(s string) int {
+	return len(s) - len(strings.TrimLeft(s, " "))
+}
+
+// isURLStart reports whether s looks like the beginning of a link rather
+// than the continuation of a wrapped one
+func isURLStart(s string) bool {
+	return strings.Contains(s, "://") || strings.HasPrefix(s, "mailto:")
+}
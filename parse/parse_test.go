@@ -0,0 +1,225 @@
+package parse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/dispatcher"
+	"github.com/martencassel/opsmsg/encode"
+	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/render"
+	"github.com/sirupsen/logrus"
+)
+
+var roundTripMessage = message.Message{
+	ID:        "SRV002",
+	Severity:  message.Error,
+	Text:      "Failed to bind to port {port} on {host}",
+	Context:   map[string]string{"port": "8080", "host": "web-1.example.com", "owner": "nginx worker process", "zone": "eu-north-1"},
+	Timestamp: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	Help:      "Cause: Another process is listening on the port. Recovery: Stop it or configure a different port.",
+	Replies:   []string{"retry", "use another port"},
+	Links:     []string{"https://runbooks.example.com/network/SRV002", "https://status.example.com/"},
+}
+
+// rendered is msg as the text layouts show it: placeholders filled in
+func rendered(msg message.Message) message.Message {
+	msg.Text = msg.Render()
+	return msg
+}
+
+// parseAll reads every message from out
+func parseAll(t *testing.T, p Parser, out string) []message.Message {
+	t.Helper()
+	var msgs []message.Message
+	s := p.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		msgs = append(msgs, s.Message())
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return msgs
+}
+
+// roundTrip checks that out, surrounded by unrelated lines, parses back
+// to want
+func roundTrip(t *testing.T, p Parser, out string, want message.Message) {
+	t.Helper()
+	out = "unrelated line\n" + out + "another one\n"
+	msgs := parseAll(t, p, out)
+	if len(msgs) != 1 {
+		t.Fatalf("parsed %d messages from:\n%s", len(msgs), out)
+	}
+	if got := msgs[0]; !reflect.DeepEqual(got, want) {
+		t.Errorf("parsed\n%#v\nwant\n%#v\nfrom:\n%s", got, want, out)
+	}
+}
+
+func renderText(t *testing.T, r render.Renderer, msg message.Message) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.Render(&buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestBoxRoundTrip(t *testing.T) {
+	plain := render.Options{DisableColors: true, Hyperlinks: render.HyperlinksOff}
+	colored := render.Options{ColorMode: render.Color256, Hyperlinks: render.HyperlinksOn}
+	for _, width := range []int{120, 80, 60, 40, 24} {
+		for name, opts := range map[string]render.Options{"plain": plain, "colored": colored} {
+			r := render.Box{Options: opts, Width: width}
+			out := renderText(t, r, roundTripMessage)
+			if name == "colored" && !strings.Contains(out, "\x1b[") {
+				t.Fatal("no colors in the colored box")
+			}
+			t.Run(fmt.Sprintf("%s/%d", name, width), func(t *testing.T) {
+				roundTrip(t, Parser{}, out, rendered(roundTripMessage))
+			})
+		}
+	}
+}
+
+func TestBoxFieldGroups(t *testing.T) {
+	r := render.Box{
+		Options: render.Options{DisableColors: true, Fields: render.FieldOrder{Groups: []render.FieldGroup{{Name: "Process", Keys: []string{"owner", "zone"}}}}},
+		Width:   50,
+	}
+	roundTrip(t, Parser{}, renderText(t, r, roundTripMessage), rendered(roundTripMessage))
+}
+
+func TestBoxCustomTimestamp(t *testing.T) {
+	r := render.Box{Options: render.Options{DisableColors: true, TimestampFormat: time.RFC1123}, Width: 40}
+	roundTrip(t, Parser{TimestampFormat: time.RFC1123}, renderText(t, r, roundTripMessage), rendered(roundTripMessage))
+}
+
+func TestSimpleRoundTrip(t *testing.T) {
+	for name, opts := range map[string]render.Options{
+		"plain":   {DisableColors: true},
+		"colored": {ColorMode: render.ColorTrue, Hyperlinks: render.HyperlinksOn},
+	} {
+		t.Run(name, func(t *testing.T) {
+			roundTrip(t, Parser{}, renderText(t, render.Simple{Options: opts}, roundTripMessage), rendered(roundTripMessage))
+		})
+	}
+}
+
+func TestClassicRoundTrip(t *testing.T) {
+	// The classic layout has no help, replies or links and leaves out the
+	// fields the text shows
+	want := rendered(roundTripMessage)
+	want.Help, want.Replies, want.Links = "", nil, nil
+	want.Context = map[string]string{"owner": "nginx worker process", "zone": "eu-north-1"}
+
+	for name, opts := range map[string]render.Options{
+		"plain":   {DisableColors: true},
+		"colored": {ColorMode: render.Color16},
+	} {
+		t.Run(name, func(t *testing.T) {
+			roundTrip(t, Parser{}, renderText(t, render.Classic{Options: opts}, roundTripMessage), want)
+		})
+	}
+
+	t.Run("ID style and timestamp format", func(t *testing.T) {
+		style := message.IDStyle{Prefix: "OPS", Suffixes: map[message.Severity]string{message.Error: "-E"}}
+		r := render.Classic{Options: render.Options{DisableColors: true, TimestampFormat: time.Stamp}, IDStyle: &style}
+		out := renderText(t, r, roundTripMessage)
+		w := want
+		w.Timestamp = time.Date(0, 5, 1, 12, 0, 0, 0, time.UTC)
+		roundTrip(t, Parser{TimestampFormat: time.Stamp, IDStyle: &style}, out, w)
+	})
+
+	t.Run("quoted values", func(t *testing.T) {
+		msg := message.Message{
+			ID: "APP100", Severity: message.Warn, Text: "Slow request", Timestamp: roundTripMessage.Timestamp,
+			Context: map[string]string{"path": `/a "b"=c`, "empty": "", "note": "tab\there\nline"},
+		}
+		roundTrip(t, Parser{}, renderText(t, render.Classic{Options: render.Options{DisableColors: true}}, msg), msg)
+	})
+}
+
+func TestClassicRejects(t *testing.T) {
+	for _, line := range []string{
+		"2024-05-01T12:00:00Z FAILURES happened", // no digit before the suffix letter
+		"2024-05-01T12:00:00Z SRV002X unknown letter",
+		"yesterday SRV002E no timestamp",
+		"2024-05-01T12:00:00Z",
+	} {
+		if msgs := parseAll(t, Parser{}, line+"\n"); len(msgs) != 0 {
+			t.Errorf("%q parsed as %+v", line, msgs)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	ecs, err := encode.ECS{ServiceName: "api"}.Encode(roundTripMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gelf, err := encode.GELF{Host: "web-1"}.Encode(roundTripMessage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var logrusOut bytes.Buffer
+	logger := logrus.New()
+	logger.Out = &logrusOut
+	logger.Formatter = &logrus.JSONFormatter{}
+	logger.ExitFunc = func(int) {}
+	dispatcher.NewLogrusDispatcher(logger).Dispatch(context.Background(), roundTripMessage)
+
+	for name, out := range map[string]string{
+		"ECS":    string(ecs) + "\n",
+		"GELF":   string(gelf) + "\n",
+		"logrus": logrusOut.String(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			want := roundTripMessage
+			msgs := parseAll(t, Parser{}, "noise\n"+out)
+			if len(msgs) != 1 {
+				t.Fatalf("parsed %d messages from %s", len(msgs), out)
+			}
+			got := msgs[0]
+			if name == "logrus" && !got.Timestamp.IsZero() {
+				// The logger stamps entries with the time of logging
+				want.Timestamp = got.Timestamp
+			}
+			if !got.Timestamp.Equal(want.Timestamp) {
+				t.Errorf("timestamp %v, want %v", got.Timestamp, want.Timestamp)
+			}
+			got.Timestamp = want.Timestamp
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parsed\n%#v\nwant\n%#v\nfrom %s", got, want, out)
+			}
+		})
+	}
+}
+
+func TestGELFContextKeys(t *testing.T) {
+	msg := roundTripMessage
+	msg.Context = map[string]string{
+		"id": "1", "ctx_id": "2", "ctx_ctx_id": "3", "help": "4",
+		"ctx_user": "5", "severity": "6", "port": "8080", "host": "h",
+	}
+	msg.Help = ""
+	data, err := encode.GELF{Host: "web-1"}.Encode(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseJSON(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Context, msg.Context) {
+		t.Errorf("context %v, want %v\nfrom %s", got.Context, msg.Context, data)
+	}
+	if got.Help != "" || got.Severity != message.Error || got.ID != "SRV002" {
+		t.Errorf("context keys overwrote message fields: %+v", got)
+	}
+}
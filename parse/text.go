@@ -0,0 +1,252 @@
+package parse
+
+import (
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+	"github.com/martencassel/opsmsg/render"
+)
+
+// boxRow is one line inside a box: the raw line, which may hold hyperlink
+// targets, and its content between the borders without padding
+type boxRow struct {
+	raw, text string
+}
+
+// box reads the rest of a render.Box message whose top border has been
+// read. ok is false when the box is cut short or has no valid header.
+func (s *Scanner) box(top string) (message.Message, bool) {
+	inner := render.DisplayWidth(top) - 4
+
+	var rows []boxRow
+	for {
+		line, ok := s.next()
+		if !ok {
+			return message.Message{}, false
+		}
+		plain := strings.TrimSpace(StripANSI(line))
+		if strings.HasPrefix(plain, "╰") {
+			break
+		}
+		if !strings.HasPrefix(plain, "│") || !strings.HasSuffix(plain, "│") {
+			// Not a box row, so the box was cut short; the line may start
+			// the next record
+			s.unread(line)
+			return message.Message{}, false
+		}
+		content := strings.TrimSuffix(strings.TrimPrefix(plain, "│"), "│")
+		content = strings.TrimRight(strings.TrimPrefix(content, " "), " ")
+		rows = append(rows, boxRow{raw: line, text: content})
+	}
+	h, n, ok := s.boxHeader(rows, inner)
+	if !ok {
+		return message.Message{}, false
+	}
+	msg := h.msg
+	body := rows[n:]
+
+	// Message text runs up to the first empty row
+	i := 0
+	text := joiner{limit: inner}
+	for ; i < len(body) && body[i].text != ""; i++ {
+		text.add(body[i].text)
+	}
+	msg.Text = text.String()
+
+	// The remaining sections are separated by empty rows
+	for i < len(body) {
+		if body[i].text == "" {
+			i++
+			continue
+		}
+		j := i
+		for j < len(body) && body[j].text != "" {
+			j++
+		}
+		section := body[i:j]
+		switch first := section[0].text; {
+		case strings.HasPrefix(first, "Help: "):
+			msg.Help = boxHelp(section, inner)
+		case first == "Reply with:":
+			msg.Replies = boxReplies(section[1:], inner)
+		case first == "Links:":
+			msg.Links = boxLinks(section[1:])
+		default:
+			boxFields(section, inner, msg.Context)
+		}
+		i = j
+	}
+	return msg, true
+}
+
+// boxHeader reads the header, which narrow boxes wrap over several rows,
+// and returns the number of rows it takes. The joined rows can only
+// match the header pattern once they end with the severity, so the first
+// match is the whole header.
+func (s *Scanner) boxHeader(rows []boxRow, inner int) (header, int, bool) {
+	joined := joiner{limit: inner}
+	for i, r := range rows {
+		if r.text == "" {
+			break
+		}
+		joined.add(r.text)
+		if h, ok := s.p.header(joined.String()); ok && !h.simple {
+			return h, i + 1, true
+		}
+	}
+	return header{}, 0, false
+}
+
+// boxFields reads "key=value" rows indented by four spaces, or six under a
+// group heading. Wrapped values continue two spaces further in.
+func boxFields(rows []boxRow, inner int, ctx map[string]string) {
+	base := 4
+	var cur *joiner
+	flush := func() {
+		if cur != nil {
+			if k, v, ok := field(cur.String()); ok {
+				ctx[k] = v
+			}
+			cur = nil
+		}
+	}
+	for _, r := range rows {
+		n := indentOf(r.text)
+		t := r.text[n:]
+		switch {
+		case n == 4 && strings.HasSuffix(t, ":") && !strings.Contains(t, "="):
+			flush()
+			base = 6
+		case n == base+2 && cur != nil:
+			cur.add(t)
+		default:
+			flush()
+			cur = &joiner{limit: inner - base - 2}
+			cur.add(t)
+		}
+	}
+	flush()
+}
+
+func boxHelp(rows []boxRow, inner int) string {
+	help := joiner{limit: inner - 6}
+	help.add(strings.TrimPrefix(rows[0].text, "Help: "))
+	for _, r := range rows[1:] {
+		help.add(strings.TrimLeft(r.text, " "))
+	}
+	return help.String()
+}
+
+// boxReplies reads "  1. reply" rows; wrapped replies continue under the
+// text
+func boxReplies(rows []boxRow, inner int) []string {
+	var replies []string
+	var cur *joiner
+	for _, r := range rows {
+		n := indentOf(r.text)
+		if m := replyPattern.FindStringSubmatch(r.text[n:]); m != nil && n == 2 {
+			if cur != nil {
+				replies = append(replies, cur.String())
+			}
+			cur = &joiner{limit: inner - len("  "+m[1]+". ")}
+			cur.add(m[2])
+		} else if cur != nil {
+			cur.add(r.text[n:])
+		}
+	}
+	if cur != nil {
+		replies = append(replies, cur.String())
+	}
+	return replies
+}
+
+// boxLinks reads link rows. Long URLs are split over several rows; with
+// hyperlinks each piece carries the whole URL, otherwise pieces that do
+// not start a new URL are appended to the previous one.
+func boxLinks(rows []boxRow) []string {
+	var links []string
+	prev := ""
+	for _, r := range rows {
+		piece := strings.TrimSpace(r.text)
+		if target := linkTarget(r.raw); target != "" {
+			if target != prev {
+				links = append(links, target)
+			}
+			prev = target
+			continue
+		}
+		prev = ""
+		if len(links) == 0 || isURLStart(piece) {
+			links = append(links, piece)
+		} else {
+			links[len(links)-1] += piece
+		}
+	}
+	return links
+}
+
+// simple reads the indented lines that follow a render.Simple header
+func (s *Scanner) simple(h header) message.Message {
+	msg := h.msg
+	mode := ""
+	for {
+		line, ok := s.next()
+		if !ok {
+			break
+		}
+		plain := StripANSI(line)
+		if !strings.HasPrefix(plain, "    ") {
+			s.unread(line)
+			break
+		}
+		n := indentOf(plain)
+		t := plain[n:]
+		switch {
+		case n == 4 && strings.HasPrefix(t, "Help: "):
+			msg.Help = strings.TrimPrefix(t, "Help: ")
+			mode = ""
+		case n == 4 && t == "Reply with:":
+			mode = "replies"
+		case n == 4 && t == "Links:":
+			mode = "links"
+		case n == 4 && strings.HasSuffix(t, ":") && !strings.Contains(t, "="):
+			mode = "group"
+		case n == 6 && mode == "replies":
+			if m := replyPattern.FindStringSubmatch(t); m != nil {
+				msg.Replies = append(msg.Replies, m[2])
+			}
+		case n == 6 && mode == "links":
+			if target := linkTarget(line); target != "" {
+				t = target
+			}
+			msg.Links = append(msg.Links, t)
+		default:
+			if k, v, ok := field(t); ok {
+				msg.Context[k] = v
+			}
+		}
+	}
+	return msg
+}
+
+// joiner reassembles text that render.Wrap split into lines of at most
+// limit cells. Wrap only breaks words longer than a line, which then fill
+// lines of their own, so a line that is one word filling the limit is
+// joined to the next without a space.
+type joiner struct {
+	limit  int
+	b      strings.Builder
+	broken bool
+}
+
+func (j *joiner) add(line string) {
+	if j.b.Len() > 0 && !j.broken {
+		j.b.WriteString(" ")
+	}
+	j.b.WriteString(line)
+	j.broken = render.DisplayWidth(line) >= j.limit && !strings.Contains(line, " ")
+}
+
+func (j *joiner) String() string {
+	return j.b.String()
+}
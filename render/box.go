@@ -0,0 +1,138 @@
+package render
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Box renders a message inside a rounded box
+type Box struct {
+	Options
+	// Width sets the box width (default: 80, at least MinBoxWidth)
+	Width int
+}
+
+// Render writes msg to w as a box
+func (r Box) Render(w io.Writer, msg message.Message) error {
+	width := r.Width
+	if width == 0 {
+		width = 80
+	}
+	if width < MinBoxWidth {
+		width = MinBoxWidth
+	}
+	p := r.palette(w, msg.Severity)
+
+	var b strings.Builder
+
+	// Content between "│ " and " │"
+	inner := width - 4
+	border := p.accent + boxVertical + p.reset
+	row := func(content string) {
+		b.WriteString(border)
+		b.WriteString(" ")
+		b.WriteString(PadRight(content, inner))
+		b.WriteString(" ")
+		b.WriteString(border)
+		b.WriteString("\n")
+	}
+
+	// Top border
+	b.WriteString(p.accent)
+	b.WriteString(boxTopLeft)
+	b.WriteString(strings.Repeat(boxHorizontal, width-2))
+	b.WriteString(boxTopRight)
+	b.WriteString(p.reset)
+	b.WriteString("\n")
+
+	// Timestamp, ID and Severity line, wrapped in narrow boxes
+	for _, line := range Wrap(header(r.Options, p, msg), inner) {
+		row(line)
+	}
+
+	// Message text - wrap if needed
+	for _, line := range Wrap(msg.Render(), inner) {
+		row(p.text + line + p.reset)
+	}
+
+	// Context fields in display order
+	sections := r.Fields.Sections(msg)
+	if len(sections) > 1 || len(sections[0].Fields) > 0 {
+		// Empty line separator
+		row("")
+
+		// Print context fields, continuation lines indented under the key
+		for _, section := range sections {
+			indent := "    "
+			if section.Name != "" {
+				row(indent + p.label + section.Name + ":" + p.reset)
+				indent = "      "
+			}
+			for _, f := range section.Fields {
+				for i, line := range Wrap(f.Key+"="+f.Value, inner-len(indent)-2) {
+					prefix := indent
+					if i > 0 {
+						prefix = indent + "  "
+					}
+					row(prefix + p.field + line + p.reset)
+				}
+			}
+		}
+	}
+
+	// Help text if present
+	if msg.Help != "" {
+		// Empty line separator
+		row("")
+
+		// Help section, continuation lines aligned with the first
+		for i, line := range Wrap(msg.Help, inner-6) {
+			label := "      "
+			if i == 0 {
+				label = p.label + "Help:" + p.reset + " "
+			}
+			row(label + p.field + line + p.reset)
+		}
+	}
+
+	// Replies the operator may give, numbered
+	if len(msg.Replies) > 0 {
+		row("")
+		row(p.label + "Reply with:" + p.reset)
+		for i, reply := range msg.Replies {
+			number := "  " + strconv.Itoa(i+1) + ". "
+			for j, line := range Wrap(reply, inner-len(number)) {
+				prefix := number
+				if j > 0 {
+					prefix = strings.Repeat(" ", len(number))
+				}
+				row(prefix + p.field + line + p.reset)
+			}
+		}
+	}
+
+	// Runbook and documentation links; long URLs are split across rows,
+	// each piece linking to the whole URL
+	if len(msg.Links) > 0 {
+		row("")
+		row(p.label + "Links:" + p.reset)
+		for _, url := range msg.Links {
+			for _, line := range Wrap(url, inner-2) {
+				row("  " + p.field + hyperlink(p.links, url, line) + p.reset)
+			}
+		}
+	}
+
+	// Bottom border
+	b.WriteString(p.accent)
+	b.WriteString(boxBottomLeft)
+	b.WriteString(strings.Repeat(boxHorizontal, width-2))
+	b.WriteString(boxBottomRight)
+	b.WriteString(p.reset)
+	b.WriteString("\n")
+
+	return write(w, &b)
+}
@@ -0,0 +1,180 @@
+package render
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// golden compares got with testdata/name.golden, rewriting the file
+// with -update
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file:\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+var boxTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+var boxCases = []struct {
+	name  string
+	width int
+	msg   message.Message
+}{
+	{
+		// The help text contains U+2011 NON-BREAKING HYPHEN
+		name:  "sec002",
+		width: 60,
+		msg: message.Message{
+			ID:       "SEC002",
+			Severity: message.Error,
+			Text:     "Token validation failed",
+			Help:     "Cause: Provided token invalid or expired. Recovery: Ensure client refreshes token or re‑authenticates.",
+		},
+	},
+	{
+		name:  "swedish",
+		width: 50,
+		msg: message.Message{
+			ID:       "DB001",
+			Severity: message.Warn,
+			Text:     "Kunde inte ansluta till databasen på {värd}: åtkomst nekad för användaren ”söderström”",
+			Context:  map[string]string{"värd": "db-ö1.exempel.se", "användare": "söderström"},
+			Help:     "Orsak: Lösenordet har gått ut. Åtgärd: Byt lösenord i förrådet och försök igen.",
+		},
+	},
+	{
+		name:  "cjk",
+		width: 40,
+		msg: message.Message{
+			ID:       "NET010",
+			Severity: message.Critical,
+			Text:     "无法连接到数据库服务器，请检查网络配置和防火墙设置后重试。",
+			Context:  map[string]string{"ホスト": "東京-データセンター-01"},
+			Help:     "원인: 네트워크 연결이 끊어졌습니다. 조치: 다시 시도하십시오.",
+			Replies:  []string{"再試行", "中止"},
+		},
+	},
+	{
+		name:  "emoji",
+		width: 44,
+		msg: message.Message{
+			ID:       "DEP007",
+			Severity: message.Info,
+			Text:     "Deployment 🚀 finished ✅ with warnings ⚠️ for the 👩‍💻 team 🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉🎉",
+			Context:  map[string]string{"status": "✅ healthy"},
+		},
+	},
+	{
+		name:  "overlong",
+		width: 48,
+		msg: message.Message{
+			ID:       "APP500",
+			Severity: message.Error,
+			Text:     "Panic in github.com/martencassel/opsmsg/dispatcher.(*OTLPDispatcher).Flush(0xc000123456,{0x1a2b3c,0xc0000a8f00}) at /home/build/go/src/github.com/martencassel/opsmsg/dispatcher/otlp.go:123 +0x1a5",
+			Context: map[string]string{
+				"url": "https://collector.example.com/v1/logs?tenant=platform-operations&region=eu-north-1&retry=true",
+			},
+			Links: []string{"https://runbooks.example.com/operations/observability/collectors/otlp-export-failures#panic-in-flush"},
+		},
+	},
+}
+
+func TestBoxGolden(t *testing.T) {
+	for _, tc := range boxCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg := tc.msg
+			msg.Timestamp = boxTime
+			r := Box{Options: Options{DisableColors: true, Hyperlinks: HyperlinksOff}, Width: tc.width}
+
+			var buf bytes.Buffer
+			if err := r.Render(&buf, msg); err != nil {
+				t.Fatal(err)
+			}
+			golden(t, filepath.Join("box", tc.name), buf.Bytes())
+
+			// Every row, however it wraps, fills the box exactly
+			for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				if w := DisplayWidth(line); w != tc.width {
+					t.Errorf("line %d is %d cells wide, want %d: %q", i+1, w, tc.width, line)
+				}
+			}
+		})
+	}
+}
+
+func TestBoxMinWidth(t *testing.T) {
+	msg := boxCases[0].msg
+	msg.Context = map[string]string{"client": "api-gateway"}
+	msg.Replies = []string{"retry"}
+	msg.Links = []string{"https://runbooks.example.com/SEC002"}
+	for _, width := range []int{-3, 1, 2, 5, MinBoxWidth} {
+		var buf bytes.Buffer
+		r := Box{Options: Options{DisableColors: true, Hyperlinks: HyperlinksOff}, Width: width}
+		if err := r.Render(&buf, msg); err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			if w := DisplayWidth(line); w != MinBoxWidth {
+				t.Errorf("Width %d: line is %d cells wide, want %d: %q", width, w, MinBoxWidth, line)
+			}
+		}
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want int
+	}{
+		{"re‑authenticates", 16},
+		{"söderström", 10},
+		{"é", 1}, // e + combining acute
+		{"无法连接", 8},
+		{"東京-01", 7},
+		{"🚀", 2},
+		{"⚠️", 2},  // text-default symbol with VS16
+		{"👩‍💻", 2}, // ZWJ sequence
+		{"\x1b[31mred\x1b[0m", 3},
+		{"\x1b]8;;https://example.com\x1b\\link\x1b]8;;\x1b\\", 4},
+	} {
+		if got := DisplayWidth(tc.s); got != tc.want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tc.s, got, tc.want)
+		}
+	}
+}
+
+func TestWrapBreaksOverlongWords(t *testing.T) {
+	lines := Wrap("see 无法连接到数据库服务器 now", 7)
+	for _, line := range lines {
+		if w := DisplayWidth(line); w > 7 {
+			t.Errorf("line %q is %d cells wide", line, w)
+		}
+	}
+	if got := strings.Join(lines, "|"); got != "see|无法连|接到数|据库服|务器|now" {
+		t.Errorf("Wrap = %q", got)
+	}
+}
@@ -0,0 +1,251 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorMode is the color capability of an output
+type ColorMode int
+
+const (
+	// ColorAuto detects the mode from the environment and the writer
+	ColorAuto ColorMode = iota
+	// ColorNone disables all escape sequences
+	ColorNone
+	// Color16 uses the 16 standard ANSI colors
+	Color16
+	// Color256 uses the xterm 256-color palette
+	Color256
+	// ColorTrue uses 24-bit RGB colors
+	ColorTrue
+)
+
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAuto:
+		return "auto"
+	case ColorNone:
+		return "none"
+	case Color16:
+		return "16"
+	case Color256:
+		return "256"
+	case ColorTrue:
+		return "truecolor"
+	}
+	return "ColorMode(" + strconv.Itoa(int(m)) + ")"
+}
+
+// DetectColorMode determines the color capability of w. NO_COLOR disables
+// colors and FORCE_COLOR (0-3) overrides detection; otherwise colors are
+// off for TERM=dumb and for writers that are not terminals, such as pipes,
+// buffers and network connections, and the depth follows COLORTERM and
+// TERM. A writer counts as a terminal when it has an Fd method, like
+// *os.File, returning a terminal's file descriptor.
+func DetectColorMode(w io.Writer) ColorMode {
+	if os.Getenv("NO_COLOR") != "" {
+		return ColorNone
+	}
+	if force, ok := os.LookupEnv("FORCE_COLOR"); ok {
+		switch strings.ToLower(force) {
+		case "0", "false":
+			return ColorNone
+		case "2":
+			return Color256
+		case "3":
+			return ColorTrue
+		default:
+			if mode := envColorDepth(); mode > Color16 {
+				return mode
+			}
+			return Color16
+		}
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return ColorNone
+	}
+	if !isTerminal(w) {
+		return ColorNone
+	}
+	if mode := envColorDepth(); mode != ColorAuto {
+		return mode
+	}
+	return Color256
+}
+
+// envColorDepth reads the color depth advertised by the terminal
+func envColorDepth() ColorMode {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorTrue
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case strings.Contains(term, "truecolor") || strings.Contains(term, "24bit") || strings.Contains(term, "direct"):
+		return ColorTrue
+	case strings.Contains(term, "256"):
+		return Color256
+	case term == "linux" || term == "vt100" || term == "ansi":
+		return Color16
+	}
+	return ColorAuto
+}
+
+// isTerminal reports whether w writes to a terminal
+func isTerminal(w io.Writer) bool {
+	switch f := w.(type) {
+	case *os.File:
+		info, err := f.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	case interface{ Fd() uintptr }:
+		return isTerminalFd(f.Fd())
+	}
+	return false
+}
+
+// rgb is a 24-bit color
+type rgb struct{ r, g, b int }
+
+// ansiNames maps color names to the 16 standard ANSI colors
+var ansiNames = map[string]int{
+	"black": 0, "red": 1, "green": 2, "yellow": 3,
+	"blue": 4, "magenta": 5, "cyan": 6, "white": 7,
+	"bright-black": 8, "gray": 8, "grey": 8, "bright-red": 9,
+	"bright-green": 10, "bright-yellow": 11, "bright-blue": 12,
+	"bright-magenta": 13, "bright-cyan": 14, "bright-white": 15,
+}
+
+// ansi16 approximates the xterm defaults for the 16 standard colors
+var ansi16 = [16]rgb{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+var cubeLevels = [6]int{0, 95, 135, 175, 215, 255}
+
+// color is a parsed theme color. Named colors stay symbolic so they follow
+// the terminal's own palette at every depth.
+type color struct {
+	named int // 0-15, or -1
+	index int // 0-255, or -1
+	rgb   rgb
+}
+
+// parseColor accepts "#rrggbb", an xterm palette index "0"-"255" or an
+// ANSI color name such as "red" or "bright-cyan"
+func parseColor(s string) (color, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if n, ok := ansiNames[s]; ok {
+		return color{named: n, index: n, rgb: ansi16[n]}, nil
+	}
+	if strings.HasPrefix(s, "#") && len(s) == 7 {
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err == nil {
+			c := rgb{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}
+			return color{named: -1, index: -1, rgb: c}, nil
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n <= 255 {
+		return color{named: -1, index: n, rgb: paletteRGB(n)}, nil
+	}
+	return color{}, fmt.Errorf("invalid color %q", s)
+}
+
+// sgr returns the SGR parameters selecting c as foreground color in mode
+func (c color) sgr(mode ColorMode) string {
+	if c.named >= 0 {
+		return ansiFg(c.named)
+	}
+	switch mode {
+	case ColorTrue:
+		return fmt.Sprintf("38;2;%d;%d;%d", c.rgb.r, c.rgb.g, c.rgb.b)
+	case Color256:
+		idx := c.index
+		if idx < 0 {
+			idx = nearest256(c.rgb)
+		}
+		return "38;5;" + strconv.Itoa(idx)
+	default:
+		if c.index >= 0 && c.index < 16 {
+			return ansiFg(c.index)
+		}
+		return ansiFg(nearest16(c.rgb))
+	}
+}
+
+func ansiFg(n int) string {
+	if n < 8 {
+		return strconv.Itoa(30 + n)
+	}
+	return strconv.Itoa(90 + n - 8)
+}
+
+// paletteRGB returns the color of an xterm palette index
+func paletteRGB(n int) rgb {
+	switch {
+	case n < 16:
+		return ansi16[n]
+	case n < 232:
+		n -= 16
+		return rgb{cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]}
+	default:
+		v := 8 + (n-232)*10
+		return rgb{v, v, v}
+	}
+}
+
+// nearest256 maps c to the closest color of the 6x6x6 cube or gray ramp
+func nearest256(c rgb) int {
+	level := func(v int) int {
+		best := 0
+		for i, l := range cubeLevels {
+			if abs(v-l) < abs(v-cubeLevels[best]) {
+				best = i
+			}
+		}
+		return best
+	}
+	cube := 16 + 36*level(c.r) + 6*level(c.g) + level(c.b)
+
+	avg := (c.r + c.g + c.b) / 3
+	gray := 232 + (avg-8+5)/10
+	if gray < 232 {
+		gray = 232
+	} else if gray > 255 {
+		gray = 255
+	}
+
+	if distance(c, paletteRGB(gray)) < distance(c, paletteRGB(cube)) {
+		return gray
+	}
+	return cube
+}
+
+// nearest16 maps c to the closest standard ANSI color
+func nearest16(c rgb) int {
+	best := 0
+	for i, a := range ansi16 {
+		if distance(c, a) < distance(c, ansi16[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+func distance(a, b rgb) int {
+	dr, dg, db := a.r-b.r, a.g-b.g, a.b-b.b
+	return 2*dr*dr + 4*dg*dg + 3*db*db
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,106 @@
+package render
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"testing"
+)
+
+// colorEnv clears the variables color detection reads and sets vars
+func colorEnv(t *testing.T, vars map[string]string) {
+	t.Helper()
+	for _, name := range []string{"NO_COLOR", "FORCE_COLOR", "TERM", "COLORTERM"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	for name, value := range vars {
+		t.Setenv(name, value)
+	}
+}
+
+// fdWriter is a writer that is not an *os.File but exposes the file
+// descriptor it writes to, like terminal wrappers do
+type fdWriter struct{ f *os.File }
+
+func (w fdWriter) Write(p []byte) (int, error) { return w.f.Write(p) }
+func (w fdWriter) Fd() uintptr                 { return w.f.Fd() }
+
+func TestDetectColorMode(t *testing.T) {
+	// A character device stands in for a terminal
+	tty, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer tty.Close()
+	if !isTerminal(tty) {
+		t.Skipf("%s is not a character device here", os.DevNull)
+	}
+	file, err := os.Create(t.TempDir() + "/out.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pr.Close()
+	defer pw.Close()
+	conn, peer := net.Pipe()
+	defer conn.Close()
+	defer peer.Close()
+
+	term := map[string]string{"TERM": "xterm"}
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		w    io.Writer
+		want ColorMode
+	}{
+		{"terminal", term, tty, Color256},
+		{"terminal behind Fd", term, fdWriter{tty}, Color256},
+		{"bare TERM", nil, tty, Color256},
+		{"TERM 256", map[string]string{"TERM": "xterm-256color"}, tty, Color256},
+		{"TERM direct", map[string]string{"TERM": "xterm-direct"}, tty, ColorTrue},
+		{"TERM linux", map[string]string{"TERM": "linux"}, tty, Color16},
+		{"COLORTERM truecolor", map[string]string{"TERM": "xterm", "COLORTERM": "truecolor"}, tty, ColorTrue},
+		{"COLORTERM 24bit", map[string]string{"COLORTERM": "24bit"}, tty, ColorTrue},
+		{"TERM dumb", map[string]string{"TERM": "dumb", "COLORTERM": "truecolor"}, tty, ColorNone},
+		{"NO_COLOR", map[string]string{"TERM": "xterm", "NO_COLOR": "1"}, tty, ColorNone},
+		{"NO_COLOR beats FORCE_COLOR", map[string]string{"NO_COLOR": "1", "FORCE_COLOR": "3"}, tty, ColorNone},
+		{"regular file", term, file, ColorNone},
+		{"file behind Fd", term, fdWriter{file}, ColorNone},
+		{"pipe", term, pw, ColorNone},
+		{"net.Conn", term, conn, ColorNone},
+		{"buffer", term, &bytes.Buffer{}, ColorNone},
+		{"FORCE_COLOR 0", map[string]string{"FORCE_COLOR": "0"}, tty, ColorNone},
+		{"FORCE_COLOR false", map[string]string{"FORCE_COLOR": "false"}, tty, ColorNone},
+		{"FORCE_COLOR 1 on a buffer", map[string]string{"FORCE_COLOR": "1"}, &bytes.Buffer{}, Color16},
+		{"FORCE_COLOR 1 keeps a deeper TERM", map[string]string{"FORCE_COLOR": "1", "TERM": "xterm-256color"}, &bytes.Buffer{}, Color256},
+		{"FORCE_COLOR 2", map[string]string{"FORCE_COLOR": "2"}, pw, Color256},
+		{"FORCE_COLOR 3", map[string]string{"FORCE_COLOR": "3", "TERM": "dumb"}, file, ColorTrue},
+		{"FORCE_COLOR empty", map[string]string{"FORCE_COLOR": ""}, &bytes.Buffer{}, Color16},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			colorEnv(t, tc.env)
+			if got := DetectColorMode(tc.w); got != tc.want {
+				t.Errorf("DetectColorMode = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDetectHyperlinksNonTerminal(t *testing.T) {
+	colorEnv(t, map[string]string{"TERM_PROGRAM": "iTerm.app"})
+	t.Setenv("FORCE_HYPERLINK", "")
+	os.Unsetenv("FORCE_HYPERLINK")
+	if DetectHyperlinks(&bytes.Buffer{}) {
+		t.Error("hyperlinks detected for a buffer")
+	}
+	t.Setenv("FORCE_HYPERLINK", "1")
+	if !DetectHyperlinks(&bytes.Buffer{}) {
+		t.Error("FORCE_HYPERLINK=1 ignored")
+	}
+}
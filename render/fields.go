@@ -0,0 +1,103 @@
+package render
+
+import (
+	"github.com/martencassel/opsmsg/message"
+)
+
+// FieldOrder controls which context fields are shown and in which order.
+// By default fields appear in the order their placeholders occur in the
+// message text, followed by the remaining fields in alphabetical order.
+type FieldOrder struct {
+	// First lists keys shown before all others, in this order
+	First []string
+	// Hide lists keys that are never shown
+	Hide []string
+	// Groups collects keys under a heading, shown after the ungrouped
+	// fields
+	Groups []FieldGroup
+}
+
+// FieldGroup is a named set of context fields
+type FieldGroup struct {
+	Name string
+	Keys []string
+}
+
+// Field is a context field ready to be rendered
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Section is a run of fields under an optional group heading
+type Section struct {
+	Name   string
+	Fields []Field
+}
+
+// Sections returns the visible context fields of msg in display order.
+// The first section holds the ungrouped fields and has an empty name.
+func (o FieldOrder) Sections(msg message.Message) []Section {
+	hidden := make(map[string]bool, len(o.Hide))
+	for _, k := range o.Hide {
+		hidden[k] = true
+	}
+	grouped := make(map[string]bool)
+	for _, g := range o.Groups {
+		for _, k := range g.Keys {
+			grouped[k] = true
+		}
+	}
+
+	visible := func(k string) bool {
+		_, ok := msg.Context[k]
+		return ok && !hidden[k]
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	add := func(k string) {
+		if !seen[k] && visible(k) && !grouped[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	for _, k := range o.First {
+		add(k)
+	}
+	for _, k := range msg.ContextKeys() {
+		add(k)
+	}
+
+	sections := []Section{{Fields: fieldsFor(msg.Context, keys)}}
+	for _, g := range o.Groups {
+		var groupKeys []string
+		for _, k := range g.Keys {
+			if visible(k) {
+				groupKeys = append(groupKeys, k)
+			}
+		}
+		if len(groupKeys) > 0 {
+			sections = append(sections, Section{Name: g.Name, Fields: fieldsFor(msg.Context, groupKeys)})
+		}
+	}
+	return sections
+}
+
+// Fields returns the visible context fields of msg in display order,
+// flattening any groups
+func (o FieldOrder) Fields(msg message.Message) []Field {
+	var fields []Field
+	for _, s := range o.Sections(msg) {
+		fields = append(fields, s.Fields...)
+	}
+	return fields
+}
+
+func fieldsFor(ctx map[string]string, keys []string) []Field {
+	fields := make([]Field, len(keys))
+	for i, k := range keys {
+		fields[i] = Field{Key: k, Value: ctx[k]}
+	}
+	return fields
+}
@@ -0,0 +1,84 @@
+package render
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// sectionString writes sections as "a b | Net: c d", with a leading empty
+// name for the ungrouped fields
+func sectionString(sections []Section) string {
+	var parts []string
+	for _, s := range sections {
+		var keys []string
+		for _, f := range s.Fields {
+			keys = append(keys, f.Key)
+		}
+		part := strings.Join(keys, " ")
+		if s.Name != "" {
+			part = s.Name + ": " + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, " | ")
+}
+
+func TestFieldOrderSections(t *testing.T) {
+	msg := message.Message{
+		ID:   "SRV002",
+		Text: "Failed to bind {host}:{port} as {user}",
+		Context: map[string]string{
+			"host": "db1", "port": "5432", "user": "app",
+			"zone": "eu-1", "attempt": "3", "pid": "42",
+		},
+	}
+	for _, tc := range []struct {
+		name  string
+		order FieldOrder
+		want  string
+	}{
+		{"default", FieldOrder{}, "host port user attempt pid zone"},
+		{"first", FieldOrder{First: []string{"zone", "user"}}, "zone user host port attempt pid"},
+		{"hide", FieldOrder{Hide: []string{"pid", "port"}}, "host user attempt zone"},
+		{
+			"groups",
+			FieldOrder{Groups: []FieldGroup{{Name: "Net", Keys: []string{"port", "host"}}, {Name: "Proc", Keys: []string{"pid"}}}},
+			"user attempt zone | Net: port host | Proc: pid",
+		},
+		{
+			"grouped first and hidden keys",
+			FieldOrder{First: []string{"pid"}, Hide: []string{"host"}, Groups: []FieldGroup{{Name: "Proc", Keys: []string{"pid", "host"}}}},
+			"port user attempt zone | Proc: pid",
+		},
+		{
+			"unknown names",
+			FieldOrder{First: []string{"nope"}, Hide: []string{"gone"}, Groups: []FieldGroup{{Name: "Empty", Keys: []string{"missing"}}}},
+			"host port user attempt pid zone",
+		},
+		{"everything hidden", FieldOrder{Hide: []string{"host", "port", "user", "zone", "attempt", "pid"}}, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sections := tc.order.Sections(msg)
+			if got := sectionString(sections); got != tc.want {
+				t.Errorf("Sections = %q, want %q", got, tc.want)
+			}
+			if sections[0].Name != "" {
+				t.Errorf("first section is named %q", sections[0].Name)
+			}
+
+			var flat []string
+			for _, f := range tc.order.Fields(msg) {
+				if f.Value != msg.Context[f.Key] {
+					t.Errorf("field %s = %q", f.Key, f.Value)
+				}
+				flat = append(flat, f.Key)
+			}
+			want := strings.NewReplacer(" | ", " ", "Net: ", "", "Proc: ", "").Replace(tc.want)
+			if got := strings.Join(flat, " "); got != want {
+				t.Errorf("Fields = %q, want %q", got, want)
+			}
+		})
+	}
+}
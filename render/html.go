@@ -0,0 +1,130 @@
+package render
+
+import (
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// HTMLStyle is a stylesheet for the classes HTML emits
+const HTMLStyle = `.opsmsg { border-left: 4px solid #888; margin: 0 0 1em; padding: .5em 1em; font-family: sans-serif; }
+.opsmsg-info { border-color: #0a84c6; }
+.opsmsg-warn { border-color: #d9a400; }
+.opsmsg-error { border-color: #d0312d; }
+.opsmsg-critical { border-color: #8b0000; background: #fff0f0; }
+.opsmsg header { color: #555; font-size: .9em; }
+.opsmsg-id { font-weight: bold; }
+.opsmsg-severity { text-transform: uppercase; font-weight: bold; }
+.opsmsg-text { font-weight: bold; margin: .3em 0; }
+.opsmsg-fields dt { font-family: monospace; float: left; clear: left; min-width: 10em; }
+.opsmsg-fields dd { font-family: monospace; margin-left: 11em; }
+.opsmsg-help { color: #333; }
+`
+
+// HTML renders messages as semantic HTML. Each message is an <article>
+// with the classes "opsmsg" and "opsmsg-<severity>"; all content is
+// escaped. Only http, https and mailto links become anchors.
+type HTML struct {
+	Options
+	// Stylesheet includes HTMLStyle in a <style> element
+	Stylesheet bool
+}
+
+// Render writes msg to w as an HTML fragment
+func (r HTML) Render(w io.Writer, msg message.Message) error {
+	var b strings.Builder
+	if r.Stylesheet {
+		b.WriteString("<style>\n" + HTMLStyle + "</style>\n")
+	}
+	r.article(&b, msg)
+	return write(w, &b)
+}
+
+// RenderBatch writes msgs to w as one <section>
+func (r HTML) RenderBatch(w io.Writer, msgs []message.Message) error {
+	var b strings.Builder
+	if r.Stylesheet {
+		b.WriteString("<style>\n" + HTMLStyle + "</style>\n")
+	}
+	b.WriteString("<section class=\"opsmsg-batch\">\n")
+	for _, msg := range msgs {
+		r.article(&b, msg)
+	}
+	b.WriteString("</section>\n")
+	return write(w, &b)
+}
+
+func (r HTML) article(b *strings.Builder, msg message.Message) {
+	esc := html.EscapeString
+	sev := strings.ToLower(string(msg.Severity))
+	if sev == "" {
+		sev = "unknown"
+	}
+
+	b.WriteString(`<article class="opsmsg opsmsg-` + esc(sev) + `" data-id="` + esc(messageID(msg)) + `">` + "\n")
+
+	b.WriteString("<header>")
+	if !msg.Timestamp.IsZero() {
+		b.WriteString(`<time datetime="` + msg.Timestamp.Format(time.RFC3339) + `">` + esc(r.timestamp(msg.Timestamp)) + "</time> ")
+	}
+	b.WriteString(`<code class="opsmsg-id">` + esc(messageID(msg)) + "</code> ")
+	b.WriteString(`<span class="opsmsg-severity">` + esc(string(msg.Severity)) + "</span>")
+	b.WriteString("</header>\n")
+
+	b.WriteString(`<p class="opsmsg-text">` + esc(msg.Render()) + "</p>\n")
+
+	for _, section := range r.Fields.Sections(msg) {
+		if len(section.Fields) == 0 {
+			continue
+		}
+		if section.Name != "" {
+			b.WriteString(`<h4 class="opsmsg-group">` + esc(section.Name) + "</h4>\n")
+		}
+		b.WriteString(`<dl class="opsmsg-fields">` + "\n")
+		for _, f := range section.Fields {
+			b.WriteString("<dt>" + esc(f.Key) + "</dt><dd>" + esc(f.Value) + "</dd>\n")
+		}
+		b.WriteString("</dl>\n")
+	}
+
+	if msg.Help != "" {
+		b.WriteString(`<p class="opsmsg-help"><strong>Help:</strong> ` + esc(msg.Help) + "</p>\n")
+	}
+
+	if len(msg.Replies) > 0 {
+		b.WriteString(`<div class="opsmsg-replies"><strong>Reply with:</strong>` + "\n<ol>\n")
+		for _, reply := range msg.Replies {
+			b.WriteString("<li>" + esc(reply) + "</li>\n")
+		}
+		b.WriteString("</ol>\n</div>\n")
+	}
+
+	if len(msg.Links) > 0 {
+		b.WriteString(`<ul class="opsmsg-links">` + "\n")
+		for _, url := range msg.Links {
+			if SafeURL(url) {
+				b.WriteString(`<li><a href="` + esc(url) + `">` + esc(url) + "</a></li>\n")
+			} else {
+				b.WriteString("<li>" + esc(url) + "</li>\n")
+			}
+		}
+		b.WriteString("</ul>\n")
+	}
+
+	b.WriteString("</article>\n")
+}
+
+// SafeURL reports whether url may be used as a link target: only http,
+// https and mailto links are made clickable
+func SafeURL(url string) bool {
+	lower := strings.ToLower(strings.TrimSpace(url))
+	for _, scheme := range []string{"http://", "https://", "mailto:"} {
+		if strings.HasPrefix(lower, scheme) {
+			return true
+		}
+	}
+	return false
+}
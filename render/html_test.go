@@ -0,0 +1,99 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestSafeURL(t *testing.T) {
+	for url, want := range map[string]bool{
+		"https://runbooks.example.com/SRV002": true,
+		"HTTP://example.com":                  true,
+		" mailto:ops@example.com":             true,
+		"javascript:alert(1)":                 false,
+		"file:///etc/passwd":                  false,
+		"runbooks/SRV002":                     false,
+	} {
+		if got := SafeURL(url); got != want {
+			t.Errorf("SafeURL(%q) = %v, want %v", url, got, want)
+		}
+	}
+}
+
+// hostileMessage has markup in every field a renderer writes
+var hostileMessage = message.Message{
+	ID:       `X"1<b>`,
+	Severity: message.Error,
+	Text:     "Use &lt; & <script>alert(1)</script> for *{who}*",
+	Context:  map[string]string{"who": "a|b\nc", "k<1>": "`tick` [x](y) #1 ~z~ _u_ \\"},
+	Help:     "Cause: <b>bold</b> & co.\nRecovery: retry.",
+	Replies:  []string{"<yes>", "no & never"},
+	Links:    []string{"https://example.com/a?b=1&c=<2>", "javascript:alert(1)", "https://example.com/with space"},
+}
+
+func TestHTMLEscaping(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (HTML{}).Render(&buf, hostileMessage); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		`<article class="opsmsg opsmsg-error" data-id="X&#34;1&lt;b&gt;">`,
+		`<code class="opsmsg-id">X&#34;1&lt;b&gt;</code>`,
+		`<p class="opsmsg-text">Use &amp;lt; &amp; &lt;script&gt;alert(1)&lt;/script&gt; for *a|b` + "\nc*</p>",
+		"<dt>k&lt;1&gt;</dt>",
+		"<strong>Help:</strong> Cause: &lt;b&gt;bold&lt;/b&gt; &amp; co.",
+		"<li>&lt;yes&gt;</li>",
+		"<li>no &amp; never</li>",
+		`<li><a href="https://example.com/a?b=1&amp;c=&lt;2&gt;">https://example.com/a?b=1&amp;c=&lt;2&gt;</a></li>`,
+		"<li>javascript:alert(1)</li>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %s in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "<script>") || strings.Contains(got, "<b>") || strings.Contains(got, `href="javascript:`) {
+		t.Errorf("unescaped markup in:\n%s", got)
+	}
+}
+
+func TestMarkdownEscaping(t *testing.T) {
+	for in, want := range map[string]string{
+		"&lt;":           "&amp;lt;",
+		"a & b":          "a &amp; b",
+		"<b>":            "&lt;b&gt;",
+		"*x* _y_ ~z~":    `\*x\* \_y\_ \~z\~`,
+		"[t](u) #h":      `\[t\](u) \#h`,
+		"`c` \\ |":       "\\`c\\` \\\\ \\|",
+		"line\nbreak":    "line  \nbreak",
+		"plain text 123": "plain text 123",
+	} {
+		if got := EscapeMarkdown(in); got != want {
+			t.Errorf("EscapeMarkdown(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (Markdown{}).Render(&buf, hostileMessage); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"**X\"1&lt;b&gt;** (ERROR)",
+		"Use &amp;lt; &amp; &lt;script&gt;alert(1)&lt;/script&gt; for \\*a\\|b  \nc\\*",
+		"| who | a\\|b<br>c |",
+		"| k&lt;1&gt; | \\`tick\\` \\[x\\](y) \\#1 \\~z\\~ \\_u\\_ \\\\ |",
+		"**Help:** Cause: &lt;b&gt;bold&lt;/b&gt; &amp; co.  \nRecovery: retry.",
+		"1. &lt;yes&gt;\n2. no &amp; never\n",
+		"- javascript:alert(1)\n",
+		"- https://example.com/with space\n",
+		"- https://example.com/a?b=1&amp;c=&lt;2&gt;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
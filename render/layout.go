@@ -0,0 +1,221 @@
+package render
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// cluster is the smallest unit the layout engine moves around: an ANSI
+// escape sequence, or a base rune together with its combining marks.
+type cluster struct {
+	text  string
+	width int
+}
+
+// splitClusters splits s into clusters, measuring each in terminal cells
+func splitClusters(s string) []cluster {
+	var clusters []cluster
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			n := escapeLen(s[i:])
+			clusters = append(clusters, cluster{s[i : i+n], 0})
+			i += n
+			continue
+		}
+
+		r, n := utf8.DecodeRuneInString(s[i:])
+		w := runeWidth(r)
+		if len(clusters) > 0 && strings.HasSuffix(clusters[len(clusters)-1].text, "\u200d") {
+			// A zero width joiner fuses the next rune into the same
+			// glyph, as in 👩‍💻
+			last := &clusters[len(clusters)-1]
+			last.text += s[i : i+n]
+			if w > last.width {
+				last.width = w
+			}
+		} else if w == 0 && len(clusters) > 0 && !isEscape(clusters[len(clusters)-1].text) {
+			// Attach combining marks, joiners and variation selectors to
+			// the preceding rune. VS16 requests emoji presentation, which
+			// terminals draw two cells wide.
+			last := &clusters[len(clusters)-1]
+			last.text += s[i : i+n]
+			if r == 0xFE0F && last.width == 1 {
+				last.width = 2
+			}
+		} else {
+			clusters = append(clusters, cluster{s[i : i+n], w})
+		}
+		i += n
+	}
+	return clusters
+}
+
+func isEscape(s string) bool {
+	return len(s) > 0 && s[0] == '\x1b'
+}
+
+// escapeLen returns the length of the ANSI escape sequence at the start of
+// s: CSI sequences such as colors, and OSC sequences such as hyperlinks
+func escapeLen(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+	default:
+		return 2
+	}
+	return len(s)
+}
+
+// DisplayWidth returns the number of terminal cells s occupies, ignoring
+// ANSI escape sequences
+func DisplayWidth(s string) int {
+	width := 0
+	for _, c := range splitClusters(s) {
+		width += c.width
+	}
+	return width
+}
+
+// runeWidth returns the number of terminal cells r occupies
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || (r >= 0x7f && r < 0xa0):
+		return 0
+	case r < 0x300:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1160 && r <= 0x11ff:
+		// Hangul medial vowels and final consonants combine with the
+		// preceding initial
+		return 0
+	case isWide(r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// wideRanges lists the East Asian Wide and Fullwidth ranges, including
+// emoji with default emoji presentation
+var wideRanges = [][2]rune{
+	{0x1100, 0x115f}, {0x231a, 0x231b}, {0x2329, 0x232a}, {0x23e9, 0x23ec},
+	{0x23f0, 0x23f0}, {0x23f3, 0x23f3}, {0x25fd, 0x25fe}, {0x2614, 0x2615},
+	{0x2648, 0x2653}, {0x267f, 0x267f}, {0x2693, 0x2693}, {0x26a1, 0x26a1},
+	{0x26aa, 0x26ab}, {0x26bd, 0x26be}, {0x26c4, 0x26c5}, {0x26ce, 0x26ce},
+	{0x26d4, 0x26d4}, {0x26ea, 0x26ea}, {0x26f2, 0x26f3}, {0x26f5, 0x26f5},
+	{0x26fa, 0x26fa}, {0x26fd, 0x26fd}, {0x2705, 0x2705}, {0x270a, 0x270b},
+	{0x2728, 0x2728}, {0x274c, 0x274c}, {0x274e, 0x274e}, {0x2753, 0x2755},
+	{0x2757, 0x2757}, {0x2795, 0x2797}, {0x27b0, 0x27b0}, {0x27bf, 0x27bf},
+	{0x2b1b, 0x2b1c}, {0x2b50, 0x2b50}, {0x2b55, 0x2b55}, {0x2e80, 0x303e},
+	{0x3041, 0x33ff}, {0x3400, 0x4dbf}, {0x4e00, 0x9fff}, {0xa000, 0xa4cf},
+	{0xa960, 0xa97f}, {0xac00, 0xd7a3}, {0xf900, 0xfaff}, {0xfe10, 0xfe19},
+	{0xfe30, 0xfe6f}, {0xff00, 0xff60}, {0xffe0, 0xffe6}, {0x16fe0, 0x16fe4},
+	{0x17000, 0x18aff}, {0x1b000, 0x1b2ff}, {0x1f004, 0x1f004}, {0x1f0cf, 0x1f0cf},
+	{0x1f18e, 0x1f18e}, {0x1f191, 0x1f19a}, {0x1f200, 0x1f202}, {0x1f210, 0x1f23b},
+	{0x1f240, 0x1f248}, {0x1f250, 0x1f251}, {0x1f260, 0x1f265}, {0x1f300, 0x1f320},
+	{0x1f32d, 0x1f335}, {0x1f337, 0x1f37c}, {0x1f37e, 0x1f393}, {0x1f3a0, 0x1f3ca},
+	{0x1f3cf, 0x1f3d3}, {0x1f3e0, 0x1f3f0}, {0x1f3f4, 0x1f3f4}, {0x1f3f8, 0x1f43e},
+	{0x1f440, 0x1f440}, {0x1f442, 0x1f4fc}, {0x1f4ff, 0x1f53d}, {0x1f54b, 0x1f54e},
+	{0x1f550, 0x1f567}, {0x1f57a, 0x1f57a}, {0x1f595, 0x1f596}, {0x1f5a4, 0x1f5a4},
+	{0x1f5fb, 0x1f64f}, {0x1f680, 0x1f6c5}, {0x1f6cc, 0x1f6cc}, {0x1f6d0, 0x1f6d2},
+	{0x1f6d5, 0x1f6d7}, {0x1f6eb, 0x1f6ec}, {0x1f6f4, 0x1f6fc}, {0x1f7e0, 0x1f7eb},
+	{0x1f90c, 0x1f93a}, {0x1f93c, 0x1f945}, {0x1f947, 0x1f9ff}, {0x1fa70, 0x1faff},
+	{0x20000, 0x2fffd}, {0x30000, 0x3fffd},
+}
+
+func isWide(r rune) bool {
+	lo, hi := 0, len(wideRanges)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		switch {
+		case r < wideRanges[mid][0]:
+			hi = mid
+		case r > wideRanges[mid][1]:
+			lo = mid + 1
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// Wrap word-wraps text into lines of at most width cells. Words wider
+// than width are broken across lines.
+func Wrap(text string, width int) []string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	var lines []string
+	var current strings.Builder
+	currentWidth := 0
+
+	for _, word := range words {
+		wordWidth := DisplayWidth(word)
+		switch {
+		case currentWidth == 0 && wordWidth <= width:
+			current.WriteString(word)
+			currentWidth = wordWidth
+		case currentWidth > 0 && currentWidth+1+wordWidth <= width:
+			current.WriteString(" ")
+			current.WriteString(word)
+			currentWidth += 1 + wordWidth
+		default:
+			if currentWidth > 0 {
+				lines = append(lines, current.String())
+				current.Reset()
+				currentWidth = 0
+			}
+			if wordWidth <= width {
+				current.WriteString(word)
+				currentWidth = wordWidth
+				continue
+			}
+			// Hard-break the overlong word; its tail starts the next line
+			for _, c := range splitClusters(word) {
+				if currentWidth+c.width > width && currentWidth > 0 {
+					lines = append(lines, current.String())
+					current.Reset()
+					currentWidth = 0
+				}
+				current.WriteString(c.text)
+				currentWidth += c.width
+			}
+		}
+	}
+
+	if current.Len() > 0 {
+		lines = append(lines, current.String())
+	}
+	return lines
+}
+
+// PadRight pads s with spaces to width cells
+func PadRight(s string, width int) string {
+	if n := width - DisplayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
@@ -0,0 +1,241 @@
+package render
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Simple renders a header line followed by indented context fields and
+// help text
+type Simple struct {
+	Options
+}
+
+// Render writes msg to w in the simple layout
+func (r Simple) Render(w io.Writer, msg message.Message) error {
+	p := r.palette(w, msg.Severity)
+
+	var b strings.Builder
+
+	// Header line with colors
+	b.WriteString(header(r.Options, p, msg))
+	b.WriteString(": ")
+	b.WriteString(p.text)
+	b.WriteString(msg.Render())
+	b.WriteString(p.reset)
+	b.WriteString("\n")
+
+	// Context fields in display order
+	for _, section := range r.Fields.Sections(msg) {
+		indent := "    "
+		if section.Name != "" {
+			b.WriteString(indent)
+			b.WriteString(p.label)
+			b.WriteString(section.Name)
+			b.WriteString(":")
+			b.WriteString(p.reset)
+			b.WriteString("\n")
+			indent = "      "
+		}
+		for _, f := range section.Fields {
+			b.WriteString(indent)
+			b.WriteString(p.field)
+			b.WriteString(f.Key)
+			b.WriteString("=")
+			b.WriteString(f.Value)
+			b.WriteString(p.reset)
+			b.WriteString("\n")
+		}
+	}
+
+	// Help text
+	if msg.Help != "" {
+		b.WriteString("    ")
+		b.WriteString(p.label)
+		b.WriteString("Help:")
+		b.WriteString(p.reset)
+		b.WriteString(" ")
+		b.WriteString(p.field)
+		b.WriteString(msg.Help)
+		b.WriteString(p.reset)
+		b.WriteString("\n")
+	}
+
+	// Replies and links
+	if len(msg.Replies) > 0 {
+		b.WriteString("    ")
+		b.WriteString(p.label)
+		b.WriteString("Reply with:")
+		b.WriteString(p.reset)
+		b.WriteString("\n")
+		for i, reply := range msg.Replies {
+			b.WriteString("      ")
+			b.WriteString(strconv.Itoa(i + 1))
+			b.WriteString(". ")
+			b.WriteString(p.field)
+			b.WriteString(reply)
+			b.WriteString(p.reset)
+			b.WriteString("\n")
+		}
+	}
+	if len(msg.Links) > 0 {
+		b.WriteString("    ")
+		b.WriteString(p.label)
+		b.WriteString("Links:")
+		b.WriteString(p.reset)
+		b.WriteString("\n")
+		for _, url := range msg.Links {
+			b.WriteString("      ")
+			b.WriteString(p.field)
+			b.WriteString(hyperlink(p.links, url, url))
+			b.WriteString(p.reset)
+			b.WriteString("\n")
+		}
+	}
+
+	return write(w, &b)
+}
+
+// Compact renders a message on a single line with its context fields
+// appended as key=value pairs. Help text is omitted.
+type Compact struct {
+	Options
+}
+
+// Render writes msg to w as one line
+func (r Compact) Render(w io.Writer, msg message.Message) error {
+	p := r.palette(w, msg.Severity)
+
+	var b strings.Builder
+	b.WriteString(header(r.Options, p, msg))
+	b.WriteString(": ")
+	b.WriteString(p.text)
+	b.WriteString(oneLine(msg.Render()))
+	b.WriteString(p.reset)
+	for _, f := range r.Fields.Fields(msg) {
+		b.WriteString(" ")
+		b.WriteString(p.field)
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.WriteString(quoteIfNeeded(f.Value))
+		b.WriteString(p.reset)
+	}
+	b.WriteString("\n")
+
+	return write(w, &b)
+}
+
+// Plain renders a message as undecorated text without colors or
+// timestamp, suitable for pasting into tickets and chat
+type Plain struct {
+	Options
+}
+
+// Render writes msg to w as plain text
+func (r Plain) Render(w io.Writer, msg message.Message) error {
+	var b strings.Builder
+
+	b.WriteString(messageID(msg))
+	b.WriteString(" (")
+	b.WriteString(string(msg.Severity))
+	b.WriteString("): ")
+	b.WriteString(msg.Render())
+	b.WriteString("\n")
+
+	for _, section := range r.Fields.Sections(msg) {
+		indent := "  "
+		if section.Name != "" {
+			b.WriteString(indent + section.Name + ":\n")
+			indent = "    "
+		}
+		for _, f := range section.Fields {
+			b.WriteString(indent + f.Key + ": " + f.Value + "\n")
+		}
+	}
+
+	if msg.Help != "" {
+		b.WriteString("Help: ")
+		b.WriteString(msg.Help)
+		b.WriteString("\n")
+	}
+
+	if len(msg.Replies) > 0 {
+		b.WriteString("Reply with:\n")
+		for i, reply := range msg.Replies {
+			b.WriteString("  " + strconv.Itoa(i+1) + ". " + reply + "\n")
+		}
+	}
+	if len(msg.Links) > 0 {
+		b.WriteString("Links:\n")
+		for _, url := range msg.Links {
+			b.WriteString("  " + url + "\n")
+		}
+	}
+
+	return write(w, &b)
+}
+
+// oneLine collapses runs of whitespace, including newlines, to one space
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func quoteIfNeeded(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s) + `"`
+	}
+	return s
+}
+
+// Classic renders a message in the one-line mainframe console style, with
+// the severity letter appended to the ID (SRV002E). Context fields that
+// the text does not already show are appended as key=value pairs.
+type Classic struct {
+	Options
+	// IDStyle sets the suffix letters and prefix (default: message.MainframeIDs)
+	IDStyle *message.IDStyle
+}
+
+// Render writes msg to w as one line
+func (r Classic) Render(w io.Writer, msg message.Message) error {
+	p := r.palette(w, msg.Severity)
+	style := message.MainframeIDs
+	if r.IDStyle != nil {
+		style = *r.IDStyle
+	}
+
+	shown := make(map[string]bool)
+	for _, name := range message.Placeholders(msg.Text) {
+		shown[name] = true
+	}
+
+	var b strings.Builder
+	b.WriteString(p.timestamp)
+	b.WriteString(r.timestamp(msg.Timestamp))
+	b.WriteString(p.reset)
+	b.WriteString(" ")
+	b.WriteString(p.severity)
+	b.WriteString(style.Format(messageID(msg), msg.Severity))
+	b.WriteString(p.reset)
+	b.WriteString(" ")
+	b.WriteString(p.text)
+	b.WriteString(oneLine(msg.Render()))
+	b.WriteString(p.reset)
+	for _, f := range r.Fields.Fields(msg) {
+		if shown[f.Key] {
+			continue
+		}
+		b.WriteString(" ")
+		b.WriteString(p.field)
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.WriteString(quoteIfNeeded(f.Value))
+		b.WriteString(p.reset)
+	}
+	b.WriteString("\n")
+
+	return write(w, &b)
+}
@@ -0,0 +1,76 @@
+package render
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// HyperlinkMode controls whether links are written as OSC 8 terminal
+// hyperlinks
+type HyperlinkMode int
+
+const (
+	// HyperlinksAuto detects support from the environment and the writer
+	HyperlinksAuto HyperlinkMode = iota
+	// HyperlinksOff writes links as plain URLs
+	HyperlinksOff
+	// HyperlinksOn always writes OSC 8 hyperlinks
+	HyperlinksOn
+)
+
+// DetectHyperlinks reports whether w is a terminal known to support OSC 8
+// hyperlinks; see DetectColorMode for what counts as a terminal.
+// FORCE_HYPERLINK (0 or 1) overrides detection. Terminals that do not
+// understand OSC 8 may print it as garbage, so unknown terminals get
+// plain URLs.
+func DetectHyperlinks(w io.Writer) bool {
+	if force, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return force != "0" && strings.ToLower(force) != "false"
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	if !isTerminal(w) {
+		return false
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KONSOLE_VERSION") != "" || os.Getenv("DOMTERM") != "" {
+		return true
+	}
+	// GNOME Terminal and other VTE terminals since 0.50
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+	switch term := os.Getenv("TERM"); {
+	case term == "xterm-kitty", term == "alacritty", strings.HasPrefix(term, "foot"):
+		return true
+	}
+	return false
+}
+
+// hyperlinks resolves whether links are written as OSC 8 hyperlinks for
+// output to w. Output without colors gets no escape sequences at all.
+func (o Options) hyperlinks(w io.Writer) bool {
+	switch o.Hyperlinks {
+	case HyperlinksOn:
+		return true
+	case HyperlinksOff:
+		return false
+	}
+	return o.colorMode(w) != ColorNone && DetectHyperlinks(w)
+}
+
+// hyperlink returns text linked to url with OSC 8, or text itself when
+// enabled is false
+func hyperlink(enabled bool, url, text string) string {
+	if !enabled {
+		return text
+	}
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
@@ -0,0 +1,96 @@
+package render
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Markdown renders messages as GitHub-flavored Markdown for tickets and
+// status pages. Context fields become a table.
+type Markdown struct {
+	Options
+}
+
+// Render writes msg to w as Markdown
+func (r Markdown) Render(w io.Writer, msg message.Message) error {
+	var b strings.Builder
+	r.message(&b, msg)
+	return write(w, &b)
+}
+
+// RenderBatch writes msgs to w separated by horizontal rules
+func (r Markdown) RenderBatch(w io.Writer, msgs []message.Message) error {
+	var b strings.Builder
+	for i, msg := range msgs {
+		if i > 0 {
+			b.WriteString("---\n\n")
+		}
+		r.message(&b, msg)
+	}
+	return write(w, &b)
+}
+
+func (r Markdown) message(b *strings.Builder, msg message.Message) {
+	b.WriteString("**" + EscapeMarkdown(messageID(msg)) + "** (" + EscapeMarkdown(string(msg.Severity)) + ")")
+	if !msg.Timestamp.IsZero() {
+		b.WriteString(" · " + EscapeMarkdown(r.timestamp(msg.Timestamp)))
+	}
+	b.WriteString("\n\n")
+	b.WriteString(EscapeMarkdown(msg.Render()) + "\n\n")
+
+	for _, section := range r.Fields.Sections(msg) {
+		if len(section.Fields) == 0 {
+			continue
+		}
+		if section.Name != "" {
+			b.WriteString("**" + EscapeMarkdown(section.Name) + "**\n\n")
+		}
+		b.WriteString("| Field | Value |\n| --- | --- |\n")
+		for _, f := range section.Fields {
+			b.WriteString("| " + markdownCell(f.Key) + " | " + markdownCell(f.Value) + " |\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if msg.Help != "" {
+		b.WriteString("**Help:** " + EscapeMarkdown(msg.Help) + "\n\n")
+	}
+
+	if len(msg.Replies) > 0 {
+		b.WriteString("**Reply with:**\n\n")
+		for i, reply := range msg.Replies {
+			b.WriteString(strconv.Itoa(i+1) + ". " + EscapeMarkdown(reply) + "\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(msg.Links) > 0 {
+		b.WriteString("**Links:**\n\n")
+		for _, url := range msg.Links {
+			if SafeURL(url) && !strings.ContainsAny(url, "<> ") {
+				b.WriteString("- <" + url + ">\n")
+			} else {
+				b.WriteString("- " + EscapeMarkdown(url) + "\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`&`, `&amp;`, `\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `&lt;`, `>`, `&gt;`, `#`, `\#`, `|`, `\|`, `~`, `\~`,
+)
+
+// EscapeMarkdown makes s render literally, with newlines as line breaks
+func EscapeMarkdown(s string) string {
+	return strings.ReplaceAll(markdownEscaper.Replace(s), "\n", "  \n")
+}
+
+// markdownCell escapes s for a table cell, which cannot span lines
+func markdownCell(s string) string {
+	return strings.ReplaceAll(markdownEscaper.Replace(s), "\n", "<br>")
+}
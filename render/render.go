@@ -0,0 +1,117 @@
+// Package render lays out messages for humans: boxed, simple, compact
+// one-line, classic console, plain text, HTML, Markdown and user-supplied
+// templates. The logrus formatters in the dispatcher package and
+// interactive viewers share these renderers.
+package render
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+const (
+	colorReset = "\033[0m"
+
+	// Box drawing characters
+	boxTopLeft     = "╭"
+	boxTopRight    = "╮"
+	boxBottomLeft  = "╰"
+	boxBottomRight = "╯"
+	boxHorizontal  = "─"
+	boxVertical    = "│"
+)
+
+// MinBoxWidth is the narrowest box the boxed layouts draw; smaller widths
+// are raised to it
+const MinBoxWidth = 16
+
+// Renderer writes a message to w in a particular layout
+type Renderer interface {
+	Render(w io.Writer, msg message.Message) error
+}
+
+// Options are shared by all layouts
+type Options struct {
+	// DisableColors disables ANSI color output
+	DisableColors bool
+	// ColorMode sets the color depth (default: detected from the writer)
+	ColorMode ColorMode
+	// Theme sets the colors (default: DefaultTheme)
+	Theme *Theme
+	// TimestampFormat sets the timestamp format (default: RFC3339)
+	TimestampFormat string
+	// Fields controls the order and visibility of context fields
+	Fields FieldOrder
+	// Hyperlinks controls OSC 8 links (default: detected from the writer)
+	Hyperlinks HyperlinkMode
+}
+
+// palette holds the escape codes for one render, empty when colors are
+// disabled, and whether links become hyperlinks
+type palette struct {
+	accent, id, severity, timestamp, text, field, label, reset string
+	links                                                      bool
+}
+
+// colorMode resolves the color depth for output to w
+func (o Options) colorMode(w io.Writer) ColorMode {
+	if o.DisableColors {
+		return ColorNone
+	}
+	if o.ColorMode == ColorAuto {
+		return DetectColorMode(w)
+	}
+	return o.ColorMode
+}
+
+func (o Options) palette(w io.Writer, sev message.Severity) palette {
+	mode := o.colorMode(w)
+	if mode == ColorNone {
+		return palette{links: o.hyperlinks(w)}
+	}
+	t := o.Theme
+	if t == nil {
+		t = DefaultTheme
+	}
+	return palette{
+		accent:    t.Border.Sequence(mode),
+		id:        t.ID.Sequence(mode),
+		severity:  t.Severity(sev).Sequence(mode),
+		timestamp: t.Timestamp.Sequence(mode),
+		text:      t.Text.Sequence(mode),
+		field:     t.Field.Sequence(mode),
+		label:     t.Label.Sequence(mode),
+		reset:     colorReset,
+		links:     o.hyperlinks(w),
+	}
+}
+
+func (o Options) timestamp(t time.Time) string {
+	format := o.TimestampFormat
+	if format == "" {
+		format = time.RFC3339
+	}
+	return t.Format(format)
+}
+
+func messageID(msg message.Message) string {
+	if msg.ID == "" {
+		return "UNKNOWN"
+	}
+	return msg.ID
+}
+
+// header renders "[timestamp] ID (SEVERITY)"
+func header(o Options, p palette, msg message.Message) string {
+	return p.timestamp + "[" + o.timestamp(msg.Timestamp) + "]" + p.reset + " " +
+		p.id + messageID(msg) + p.reset + " " +
+		p.severity + "(" + string(msg.Severity) + ")" + p.reset
+}
+
+func write(w io.Writer, b *strings.Builder) error {
+	_, err := io.WriteString(w, b.String())
+	return err
+}
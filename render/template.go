@@ -0,0 +1,212 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Layout is a parsed text/template for the Template renderer. Besides the
+// standard template functions it may use:
+//
+//	color "id" .ID        wrap text in a theme element's color: border,
+//	                      timestamp, id, text, field, label or severity
+//	wrap 60 .Text         split text into lines of at most 60 cells
+//	pad 20 .ID            pad text with spaces to 20 cells
+//	box 80 $body          draw a border of width 80 around text
+//	letter .Severity      the severity letter, e.g. "E"
+//	link $url $text       an OSC 8 hyperlink where the terminal supports it
+//	timestamp .Timestamp  format a time with the TimestampFormat option
+//	oneline, quote, indent, upper, lower, join
+//
+// Templates are executed with a TemplateData.
+type Layout struct {
+	tmpl *template.Template
+}
+
+// TemplateData is the value a Layout is executed with
+type TemplateData struct {
+	ID        string
+	Severity  message.Severity
+	Timestamp time.Time
+	// Text is the message text with placeholders filled in
+	Text string
+	// Template is the message text as written in the catalog
+	Template string
+	Help     string
+	Replies  []string
+	Links    []string
+	Context  map[string]string
+	// Fields are the visible context fields in display order
+	Fields []Field
+	// Sections are Fields split by FieldOrder groups
+	Sections []Section
+}
+
+// ParseLayout parses text as a layout. name is used in error messages.
+func ParseLayout(name, text string) (*Layout, error) {
+	tmpl, err := template.New(name).Funcs(templateFuncs(Options{}, palette{}, nil)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	l := &Layout{tmpl: tmpl}
+
+	// Catch references to unknown fields and bad function arguments now
+	// rather than on the first message logged
+	sample := message.Message{
+		ID:        "TST001",
+		Severity:  message.Error,
+		Text:      "Sample message for {name}",
+		Context:   map[string]string{"name": "value"},
+		Timestamp: time.Now(),
+		Help:      "Sample help",
+		Replies:   []string{"retry", "cancel"},
+		Links:     []string{"https://example.com/runbook"},
+	}
+	if err := (Template{Layout: l}).Render(io.Discard, sample); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// LoadLayout reads and parses a layout file
+func LoadLayout(path string) (*Layout, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseLayout(path, string(data))
+}
+
+// Template renders a message with a user-supplied Layout. A newline is
+// added when the output does not end with one.
+type Template struct {
+	Options
+	// Layout is the template to execute
+	Layout *Layout
+	// IDStyle sets the letters returned by the letter function (default:
+	// message.MainframeIDs)
+	IDStyle *message.IDStyle
+}
+
+// Render writes msg to w using the layout
+func (r Template) Render(w io.Writer, msg message.Message) error {
+	if r.Layout == nil {
+		return fmt.Errorf("render: template has no layout")
+	}
+	p := r.palette(w, msg.Severity)
+
+	tmpl, err := r.Layout.tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	tmpl.Funcs(templateFuncs(r.Options, p, r.IDStyle))
+
+	data := TemplateData{
+		ID:        messageID(msg),
+		Severity:  msg.Severity,
+		Timestamp: msg.Timestamp,
+		Text:      msg.Render(),
+		Template:  msg.Text,
+		Help:      msg.Help,
+		Replies:   msg.Replies,
+		Links:     msg.Links,
+		Context:   msg.Context,
+		Fields:    r.Fields.Fields(msg),
+		Sections:  r.Fields.Sections(msg),
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return err
+	}
+	if !strings.HasSuffix(b.String(), "\n") {
+		b.WriteString("\n")
+	}
+	return write(w, &b)
+}
+
+func templateFuncs(o Options, p palette, style *message.IDStyle) template.FuncMap {
+	if style == nil {
+		style = &message.MainframeIDs
+	}
+	return template.FuncMap{
+		"color": func(element, s string) (string, error) {
+			codes := map[string]string{
+				"border": p.accent, "timestamp": p.timestamp, "id": p.id,
+				"text": p.text, "field": p.field, "label": p.label,
+				"severity": p.severity,
+			}
+			code, ok := codes[element]
+			if !ok {
+				return "", fmt.Errorf("unknown color element %q", element)
+			}
+			if code == "" {
+				return s, nil
+			}
+			return code + s + p.reset, nil
+		},
+		"wrap": func(width int, s string) []string {
+			return Wrap(s, width)
+		},
+		"pad": func(width int, s string) string {
+			return PadRight(s, width)
+		},
+		"box": func(width int, s string) string {
+			return boxed(p, width, s)
+		},
+		"link": func(url, text string) string {
+			return hyperlink(p.links, url, text)
+		},
+		"letter": func(sev message.Severity) string {
+			return style.Suffixes[sev]
+		},
+		"timestamp": o.timestamp,
+		"oneline":   oneLine,
+		"quote":     quoteIfNeeded,
+		"indent": func(n int, s string) string {
+			prefix := strings.Repeat(" ", n)
+			return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+		"join": func(sep string, elems []string) string {
+			return strings.Join(elems, sep)
+		},
+	}
+}
+
+// boxed draws a border of the given width around s, wrapping long lines
+func boxed(p palette, width int, s string) string {
+	if width < MinBoxWidth {
+		width = MinBoxWidth
+	}
+	inner := width - 4
+	border := p.accent + boxVertical + p.reset
+
+	var b strings.Builder
+	b.WriteString(p.accent + boxTopLeft + strings.Repeat(boxHorizontal, width-2) + boxTopRight + p.reset + "\n")
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		// Lines that fit are kept as they are so alignment survives;
+		// longer ones are wrapped under their own indentation
+		rows := []string{line}
+		if DisplayWidth(line) > inner {
+			rest := strings.TrimLeft(line, " ")
+			indent := line[:len(line)-len(rest)]
+			rows = Wrap(rest, inner-len(indent))
+			for i := range rows {
+				rows[i] = indent + rows[i]
+			}
+		}
+		for _, row := range rows {
+			b.WriteString(border + " " + PadRight(row, inner) + " " + border + "\n")
+		}
+	}
+	b.WriteString(p.accent + boxBottomLeft + strings.Repeat(boxHorizontal, width-2) + boxBottomRight + p.reset + "\n")
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+var templateMessage = message.Message{
+	ID:        "SRV002",
+	Severity:  message.Error,
+	Text:      "Failed to bind to port {port}",
+	Context:   map[string]string{"port": "8080", "owner": "nginx worker", "pid": "42"},
+	Timestamp: boxTime,
+	Help:      "Cause: port in use.\nRecovery: stop the other process.",
+	Replies:   []string{"retry", "abort"},
+	Links:     []string{"https://runbooks.example.com/SRV002"},
+}
+
+func renderTemplate(t *testing.T, r Template, msg message.Message) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := r.Render(&buf, msg); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestTemplateRender(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		layout string
+		opts   Options
+		want   string
+	}{
+		{
+			"fields",
+			`{{timestamp .Timestamp}} {{.ID}}{{letter .Severity}} {{pad 6 (lower (print .Severity))}}|{{.Text}}{{range .Fields}} {{.Key}}={{quote .Value}}{{end}}`,
+			Options{DisableColors: true},
+			"2024-05-01T12:00:00Z SRV002E error |Failed to bind to port 8080 port=8080 owner=\"nginx worker\" pid=42\n",
+		},
+		{
+			"timestamp format and sections",
+			`{{timestamp .Timestamp}} {{.Template}}{{range .Sections}} [{{.Name}}:{{range .Fields}}{{.Key}},{{end}}]{{end}}` + "\n",
+			Options{DisableColors: true, TimestampFormat: "15:04", Fields: FieldOrder{Hide: []string{"pid"}, Groups: []FieldGroup{{Name: "proc", Keys: []string{"owner"}}}}},
+			"12:00 Failed to bind to port {port} [:port,] [proc:owner,]\n",
+		},
+		{
+			"help, replies and links",
+			`{{oneline .Help}}{{"\n"}}{{indent 2 .Help}}{{"\n"}}{{join "/" .Replies}} {{range .Links}}{{link . "runbook"}}{{end}}`,
+			Options{DisableColors: true},
+			"Cause: port in use. Recovery: stop the other process.\n  Cause: port in use.\n  Recovery: stop the other process.\nretry/abort runbook\n",
+		},
+		{
+			"hyperlinks on",
+			`{{range .Links}}{{link . "runbook"}}{{end}}`,
+			Options{DisableColors: true, Hyperlinks: HyperlinksOn},
+			"\x1b]8;;https://runbooks.example.com/SRV002\x1b\\runbook\x1b]8;;\x1b\\\n",
+		},
+		{
+			"box",
+			`{{box 20 (printf "%s %s" .ID .Severity)}}`,
+			Options{DisableColors: true},
+			"╭──────────────────╮\n│ SRV002 ERROR     │\n╰──────────────────╯\n",
+		},
+		{
+			"wrap",
+			`{{range wrap 12 .Text}}{{.}}|{{end}}`,
+			Options{DisableColors: true},
+			"Failed to|bind to port|8080|\n",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := ParseLayout(tc.name, tc.layout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := renderTemplate(t, Template{Options: tc.opts, Layout: l}, templateMessage); got != tc.want {
+				t.Errorf("got\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTemplateColors(t *testing.T) {
+	l, err := ParseLayout("colors", `{{color "id" .ID}} {{color "severity" (print .Severity)}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := renderTemplate(t, Template{Options: Options{ColorMode: Color16}, Layout: l}, templateMessage)
+	if !strings.HasPrefix(got, "\x1b[") || !strings.Contains(got, "SRV002\x1b[0m ") || !strings.HasSuffix(got, "ERROR\x1b[0m\n") {
+		t.Errorf("got %q", got)
+	}
+	got = renderTemplate(t, Template{Options: Options{DisableColors: true}, Layout: l}, templateMessage)
+	if got != "SRV002 ERROR\n" {
+		t.Errorf("without colors: %q", got)
+	}
+}
+
+func TestTemplateIDStyle(t *testing.T) {
+	l, err := ParseLayout("letter", `{{.ID}}{{letter .Severity}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	style := message.IDStyle{Suffixes: map[message.Severity]string{message.Error: "-ERR"}}
+	if got := renderTemplate(t, Template{Layout: l, IDStyle: &style}, templateMessage); got != "SRV002-ERR\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseLayoutErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, layout, want string
+	}{
+		{"syntax", `{{.ID`, "unclosed action"},
+		{"unknown function", `{{shout .ID}}`, `function "shout" not defined`},
+		{"unknown field", `{{.Hostname}}`, "can't evaluate field Hostname"},
+		{"unknown color element", `{{color "banner" .ID}}`, `unknown color element "banner"`},
+		{"sample out of range", `{{index .Replies 5}}`, "out of range"},
+		{"bad argument", `{{wrap "wide" .Text}}`, "expected integer"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseLayout("bad.tmpl", tc.layout)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %v, want one containing %q", err, tc.want)
+			}
+			if err != nil && !strings.Contains(err.Error(), "bad.tmpl") {
+				t.Errorf("error %q does not name the layout", err)
+			}
+		})
+	}
+}
+
+func TestLoadLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "line.tmpl")
+	if err := os.WriteFile(path, []byte("{{.ID}} {{.Text}}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := LoadLayout(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := renderTemplate(t, Template{Options: Options{DisableColors: true}, Layout: l}, templateMessage); got != "SRV002 Failed to bind to port 8080\n" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := LoadLayout(filepath.Join(t.TempDir(), "missing.tmpl")); !os.IsNotExist(err) {
+		t.Errorf("missing file: %v", err)
+	}
+	if err := (Template{}).Render(&bytes.Buffer{}, templateMessage); err == nil {
+		t.Error("Render without a layout succeeded")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package render
+
+// isTerminalFd is only implemented for *os.File on this platform
+func isTerminalFd(fd uintptr) bool {
+	return false
+}
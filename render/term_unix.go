@@ -0,0 +1,12 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package render
+
+import "golang.org/x/sys/unix"
+
+// isTerminalFd reports whether fd is a character device such as a
+// terminal
+func isTerminalFd(fd uintptr) bool {
+	var st unix.Stat_t
+	return unix.Fstat(int(fd), &st) == nil && st.Mode&unix.S_IFMT == unix.S_IFCHR
+}
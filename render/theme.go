@@ -0,0 +1,218 @@
+package render
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/martencassel/opsmsg/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Style is the appearance of one element of a rendered message
+type Style struct {
+	// Color is "#rrggbb", an xterm palette index or an ANSI color name;
+	// empty keeps the terminal's default color
+	Color string `yaml:"color,omitempty"`
+	Bold  bool   `yaml:"bold,omitempty"`
+	Dim   bool   `yaml:"dim,omitempty"`
+}
+
+// Theme assigns styles to the elements of a rendered message
+type Theme struct {
+	Name      string `yaml:"name"`
+	Border    Style  `yaml:"border"`
+	Timestamp Style  `yaml:"timestamp"`
+	ID        Style  `yaml:"id"`
+	Text      Style  `yaml:"text"`
+	Field     Style  `yaml:"field"`
+	Label     Style  `yaml:"label"`
+	Info      Style  `yaml:"info"`
+	Warn      Style  `yaml:"warn"`
+	Error     Style  `yaml:"error"`
+	Critical  Style  `yaml:"critical"`
+	// Other styles severities the theme does not know
+	Other Style `yaml:"other"`
+}
+
+// DefaultTheme is the orange 256-color theme
+var DefaultTheme = &Theme{
+	Name:      "default",
+	Border:    Style{Color: "208"},
+	Timestamp: Style{Color: "240"},
+	ID:        Style{Color: "208", Bold: true},
+	Text:      Style{Bold: true},
+	Field:     Style{Dim: true},
+	Label:     Style{Color: "226"},
+	Info:      Style{Color: "51"},
+	Warn:      Style{Color: "226"},
+	Error:     Style{Color: "196"},
+	Critical:  Style{Color: "196"},
+	Other:     Style{Color: "208"},
+}
+
+// HighContrastTheme avoids dim and gray text for low-vision users and
+// projectors
+var HighContrastTheme = &Theme{
+	Name:      "high-contrast",
+	Border:    Style{Color: "bright-white"},
+	Timestamp: Style{Color: "bright-white"},
+	ID:        Style{Color: "bright-yellow", Bold: true},
+	Text:      Style{Color: "bright-white", Bold: true},
+	Field:     Style{Color: "bright-white"},
+	Label:     Style{Color: "bright-yellow", Bold: true},
+	Info:      Style{Color: "bright-cyan", Bold: true},
+	Warn:      Style{Color: "bright-yellow", Bold: true},
+	Error:     Style{Color: "bright-red", Bold: true},
+	Critical:  Style{Color: "bright-magenta", Bold: true},
+	Other:     Style{Color: "bright-white", Bold: true},
+}
+
+// BasicTheme uses only the 16 standard ANSI colors, so it follows the
+// terminal's palette and works on old terminals
+var BasicTheme = &Theme{
+	Name:      "16-color",
+	Border:    Style{Color: "yellow"},
+	Timestamp: Style{Color: "bright-black"},
+	ID:        Style{Color: "yellow", Bold: true},
+	Text:      Style{Bold: true},
+	Field:     Style{Dim: true},
+	Label:     Style{Color: "bright-yellow"},
+	Info:      Style{Color: "cyan"},
+	Warn:      Style{Color: "yellow"},
+	Error:     Style{Color: "red"},
+	Critical:  Style{Color: "bright-red", Bold: true},
+	Other:     Style{Color: "yellow"},
+}
+
+// MonochromeTheme uses bold and dim only
+var MonochromeTheme = &Theme{
+	Name:     "monochrome",
+	ID:       Style{Bold: true},
+	Text:     Style{Bold: true},
+	Field:    Style{Dim: true},
+	Label:    Style{Bold: true},
+	Error:    Style{Bold: true},
+	Critical: Style{Bold: true},
+}
+
+var builtinThemes = map[string]*Theme{
+	DefaultTheme.Name:      DefaultTheme,
+	HighContrastTheme.Name: HighContrastTheme,
+	BasicTheme.Name:        BasicTheme,
+	MonochromeTheme.Name:   MonochromeTheme,
+}
+
+// LookupTheme returns the built-in theme with the given name
+func LookupTheme(name string) (*Theme, bool) {
+	t, ok := builtinThemes[name]
+	return t, ok
+}
+
+// ThemeNames returns the names of the built-in themes
+func ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadTheme reads a theme from a YAML file. Elements the file does not
+// mention keep the styles of the built-in theme named by its "base" key
+// (default: "default").
+func LoadTheme(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var head struct {
+		Base string `yaml:"base"`
+	}
+	if err := yaml.Unmarshal(data, &head); err != nil {
+		return nil, err
+	}
+	base := DefaultTheme
+	if head.Base != "" {
+		var ok bool
+		if base, ok = LookupTheme(head.Base); !ok {
+			return nil, fmt.Errorf("%s: unknown base theme %q", path, head.Base)
+		}
+	}
+
+	theme := *base
+	if err := yaml.Unmarshal(data, &theme); err != nil {
+		return nil, err
+	}
+	if err := theme.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &theme, nil
+}
+
+// Validate checks that every color of the theme can be parsed
+func (t *Theme) Validate() error {
+	for name, s := range t.styles() {
+		if s.Color == "" {
+			continue
+		}
+		if _, err := parseColor(s.Color); err != nil {
+			return fmt.Errorf("theme %s: %s: %w", t.Name, name, err)
+		}
+	}
+	return nil
+}
+
+func (t *Theme) styles() map[string]Style {
+	return map[string]Style{
+		"border": t.Border, "timestamp": t.Timestamp, "id": t.ID,
+		"text": t.Text, "field": t.Field, "label": t.Label,
+		"info": t.Info, "warn": t.Warn, "error": t.Error,
+		"critical": t.Critical, "other": t.Other,
+	}
+}
+
+// Severity returns the style for sev. Logrus level names are accepted so
+// entries logged without a severity field are styled by their level.
+func (t *Theme) Severity(sev message.Severity) Style {
+	switch strings.ToUpper(string(sev)) {
+	case "CRITICAL", "FATAL", "PANIC":
+		return t.Critical
+	case "ERROR":
+		return t.Error
+	case "WARN", "WARNING":
+		return t.Warn
+	case "INFO":
+		return t.Info
+	default:
+		return t.Other
+	}
+}
+
+// Sequence returns the escape sequence that switches to s in mode, or ""
+// when mode is ColorNone or s is empty. Colors the terminal cannot show
+// are downgraded to the nearest available one.
+func (s Style) Sequence(mode ColorMode) string {
+	if mode == ColorNone {
+		return ""
+	}
+	var params []string
+	if s.Bold {
+		params = append(params, "1")
+	}
+	if s.Dim {
+		params = append(params, "2")
+	}
+	if s.Color != "" {
+		if c, err := parseColor(s.Color); err == nil {
+			params = append(params, c.sgr(mode))
+		}
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\033[" + strings.Join(params, ";") + "m"
+}
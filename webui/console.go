@@ -0,0 +1,289 @@
+// Package webui is a browser interface for operators: a catalog browser
+// with search and per-message detail, and a live console of recently
+// dispatched messages where pending replies can be answered. Console is
+// both the dispatcher that feeds the console and the http.Handler that
+// serves it; it uses only net/http and assets embedded in the binary.
+//
+//	c := webui.NewConsole(catalog.Builtin(), d)
+//	http.Handle("/ops/", http.StripPrefix("/ops", c))
+//	// dispatch through c instead of d
+package webui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/martencassel/opsmsg/catalog"
+	"github.com/martencassel/opsmsg/dispatcher"
+	"github.com/martencassel/opsmsg/message"
+)
+
+// Console keeps the most recent dispatched messages for the web console
+// and forwards every message to the next dispatcher. Messages with
+// replies stay pending until an operator answers them.
+type Console struct {
+	// Next receives every message after it has been recorded (optional)
+	Next dispatcher.Dispatcher
+	// Catalog is shown in the catalog browser
+	Catalog catalog.Catalog
+	// Size is the number of recent messages kept (default: 500). Pending
+	// messages are kept until they are answered, except that at most Size
+	// dispatched messages that no Ask waits on stay pending; the oldest
+	// of those are withdrawn first.
+	Size int
+	// OnReply is called when an operator answers a message (optional)
+	OnReply func(msg message.Message, reply string)
+
+	mu      sync.Mutex
+	seq     uint64
+	recent  []*record
+	pending map[uint64]*record
+	subs    map[chan event]struct{}
+}
+
+// record is a dispatched message as the console shows it
+type record struct {
+	Seq       uint64            `json:"seq"`
+	ID        string            `json:"id"`
+	Severity  string            `json:"severity"`
+	Text      string            `json:"text"`
+	Template  string            `json:"template"`
+	Timestamp time.Time         `json:"timestamp"`
+	Context   map[string]string `json:"context,omitempty"`
+	Help      string            `json:"help,omitempty"`
+	Replies   []string          `json:"replies,omitempty"`
+	Links     []string          `json:"links,omitempty"`
+	Pending   bool              `json:"pending"`
+	Reply     string            `json:"reply,omitempty"`
+	RepliedAt *time.Time        `json:"replied_at,omitempty"`
+
+	msg    message.Message
+	answer chan string
+}
+
+// event is sent to console subscribers: a new message, or a reply to one
+type event struct {
+	name string
+	rec  record
+}
+
+// NewConsole creates a web console for cat in front of next
+func NewConsole(cat catalog.Catalog, next dispatcher.Dispatcher) *Console {
+	return &Console{Next: next, Catalog: cat}
+}
+
+// Dispatch records msg for the console and forwards it to Next
+func (c *Console) Dispatch(ctx context.Context, msg message.Message) error {
+	c.add(msg, nil)
+	if c.Next == nil {
+		return nil
+	}
+	return c.Next.Dispatch(ctx, msg)
+}
+
+// Ask dispatches a message with replies and waits until an operator
+// answers it in the console or ctx is done. It returns the chosen reply.
+func (c *Console) Ask(ctx context.Context, msg message.Message) (string, error) {
+	if len(msg.Replies) == 0 {
+		return "", fmt.Errorf("webui: message %s has no replies", msg.ID)
+	}
+	answer := make(chan string, 1)
+	rec := c.add(msg, answer)
+	if c.Next != nil {
+		if err := c.Next.Dispatch(ctx, msg); err != nil {
+			c.cancel(rec.Seq)
+			return "", err
+		}
+	}
+	select {
+	case reply := <-answer:
+		return reply, nil
+	case <-ctx.Done():
+		c.cancel(rec.Seq)
+		return "", ctx.Err()
+	}
+}
+
+func (c *Console) add(msg message.Message, answer chan string) *record {
+	if msg.Timestamp.IsZero() {
+		msg.Timestamp = time.Now()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seq++
+	rec := &record{
+		Seq:       c.seq,
+		ID:        msg.ID,
+		Severity:  string(msg.Severity),
+		Text:      msg.Render(),
+		Template:  msg.Text,
+		Timestamp: msg.Timestamp,
+		Context:   msg.Context,
+		Help:      msg.Help,
+		Replies:   msg.Replies,
+		Links:     msg.Links,
+		Pending:   len(msg.Replies) > 0,
+		msg:       msg,
+		answer:    answer,
+	}
+	if rec.Pending {
+		if c.pending == nil {
+			c.pending = make(map[uint64]*record)
+		}
+		c.pending[rec.Seq] = rec
+		if answer == nil {
+			c.expire()
+		}
+	}
+
+	c.recent = append(c.recent, rec)
+	if over := len(c.recent) - c.size(); over > 0 {
+		c.recent = append(c.recent[:0:0], c.recent[over:]...)
+	}
+	c.publish(event{name: "message", rec: *rec})
+	return rec
+}
+
+func (c *Console) size() int {
+	if c.Size <= 0 {
+		return 500
+	}
+	return c.Size
+}
+
+// expire withdraws the oldest pending messages no Ask waits on beyond
+// the console size; c.mu must be held
+func (c *Console) expire() {
+	var unasked []*record
+	for _, rec := range c.pending {
+		if rec.answer == nil {
+			unasked = append(unasked, rec)
+		}
+	}
+	over := len(unasked) - c.size()
+	if over <= 0 {
+		return
+	}
+	sort.Slice(unasked, func(i, j int) bool { return unasked[i].Seq < unasked[j].Seq })
+	for _, rec := range unasked[:over] {
+		c.withdraw(rec)
+	}
+}
+
+// errNotPending is returned when a message was already answered
+var errNotPending = errors.New("message is not waiting for a reply")
+
+// reply answers the pending message seq. The reply must be one of the
+// message's replies; letter case is ignored.
+func (c *Console) reply(seq uint64, reply string) (record, error) {
+	c.mu.Lock()
+	rec, ok := c.pending[seq]
+	if !ok {
+		c.mu.Unlock()
+		return record{}, errNotPending
+	}
+	choice := ""
+	for _, r := range rec.Replies {
+		if strings.EqualFold(r, strings.TrimSpace(reply)) {
+			choice = r
+			break
+		}
+	}
+	if choice == "" {
+		c.mu.Unlock()
+		return record{}, fmt.Errorf("reply must be one of %s", strings.Join(rec.Replies, ", "))
+	}
+
+	now := time.Now()
+	rec.Pending, rec.Reply, rec.RepliedAt = false, choice, &now
+	delete(c.pending, seq)
+	if rec.answer != nil {
+		rec.answer <- choice
+	}
+	c.publish(event{name: "reply", rec: *rec})
+	answered := *rec
+	c.mu.Unlock()
+
+	if c.OnReply != nil {
+		c.OnReply(answered.msg, choice)
+	}
+	return answered, nil
+}
+
+// cancel withdraws a pending message whose asker stopped waiting
+func (c *Console) cancel(seq uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rec, ok := c.pending[seq]; ok {
+		c.withdraw(rec)
+	}
+}
+
+// withdraw stops rec waiting for a reply; c.mu must be held
+func (c *Console) withdraw(rec *record) {
+	rec.Pending = false
+	delete(c.pending, rec.Seq)
+	c.publish(event{name: "reply", rec: *rec})
+}
+
+// snapshot returns the recent messages and the pending ones that have
+// scrolled out, oldest first
+func (c *Console) snapshot() []record {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var recs []record
+	oldest := uint64(0)
+	if len(c.recent) > 0 {
+		oldest = c.recent[0].Seq
+	}
+	for seq, rec := range c.pending {
+		if seq < oldest {
+			recs = append(recs, *rec)
+		}
+	}
+	sortRecords(recs)
+	for _, rec := range c.recent {
+		recs = append(recs, *rec)
+	}
+	return recs
+}
+
+// subscribe returns a channel receiving every new event. A subscriber
+// that falls behind is dropped and its channel closed.
+func (c *Console) subscribe() chan event {
+	ch := make(chan event, 64)
+	c.mu.Lock()
+	if c.subs == nil {
+		c.subs = make(map[chan event]struct{})
+	}
+	c.subs[ch] = struct{}{}
+	c.mu.Unlock()
+	return ch
+}
+
+func (c *Console) unsubscribe(ch chan event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.subs[ch]; ok {
+		delete(c.subs, ch)
+		close(ch)
+	}
+}
+
+// publish sends e to the subscribers; c.mu must be held
+func (c *Console) publish(e event) {
+	for ch := range c.subs {
+		select {
+		case ch <- e:
+		default:
+			delete(c.subs, ch)
+			close(ch)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package webui
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/martencassel/opsmsg/message"
+)
+
+func TestConsolePendingExpires(t *testing.T) {
+	c := &Console{Size: 3}
+	ctx := context.Background()
+
+	asked := make(chan string, 1)
+	askCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		reply, _ := c.Ask(askCtx, message.Message{ID: "OPS000", Text: "Proceed?", Replies: []string{"yes", "no"}})
+		asked <- reply
+	}()
+	for {
+		c.mu.Lock()
+		n := len(c.pending)
+		c.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := 1; i <= 10; i++ {
+		c.Dispatch(ctx, message.Message{ID: "OPS001", Text: "Restart?", Replies: []string{"yes", "no"}})
+	}
+
+	c.mu.Lock()
+	var seqs []uint64
+	for seq := range c.pending {
+		seqs = append(seqs, seq)
+	}
+	c.mu.Unlock()
+	if len(seqs) != 4 {
+		t.Fatalf("%d messages pending, want the asked one and the newest 3", len(seqs))
+	}
+	if _, err := c.reply(2, "yes"); err != errNotPending {
+		t.Errorf("reply to an expired message: %v", err)
+	}
+	if _, err := c.reply(11, "yes"); err != nil {
+		t.Errorf("reply to the newest message: %v", err)
+	}
+
+	// The asker still waits although its message scrolled out
+	if _, err := c.reply(1, "no"); err != nil {
+		t.Fatal(err)
+	}
+	if reply := <-asked; reply != "no" {
+		t.Errorf("Ask = %q", reply)
+	}
+	if recs := c.snapshot(); len(recs) != 3 || recs[0].Seq != 9 {
+		t.Errorf("snapshot = %+v, want the newest 3", recs)
+	}
+}
@@ -0,0 +1,217 @@
+package webui
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/martencassel/opsmsg/catalog"
+)
+
+//go:embed static
+var static embed.FS
+
+// heartbeat keeps idle event streams open through proxies
+const heartbeat = 30 * time.Second
+
+// entry is a catalog entry with its help split into cause and recovery
+type entry struct {
+	catalog.CatalogEntry
+	Cause    string `json:"cause,omitempty"`
+	Recovery string `json:"recovery,omitempty"`
+}
+
+// ServeHTTP serves the console. Paths are relative, so the handler can
+// be mounted under a prefix with http.StripPrefix:
+//
+//	/                          the browser interface
+//	/static/...                its assets
+//	/api/catalog               catalog entries as JSON
+//	/api/catalog/{id}          one entry; severity letters are accepted
+//	/api/messages              recent messages as JSON
+//	/api/events                recent and new messages as Server-Sent Events
+//	/api/messages/{seq}/reply  POST reply=... answers a pending message
+func (c *Console) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := "/" + strings.TrimLeft(r.URL.Path, "/")
+	switch {
+	case path == "/" || path == "/index.html":
+		c.serveStatic(w, r, "index.html")
+	case strings.HasPrefix(path, "/static/"):
+		c.serveStatic(w, r, strings.TrimPrefix(path, "/static/"))
+	case path == "/api/catalog":
+		c.serveCatalog(w, r)
+	case strings.HasPrefix(path, "/api/catalog/"):
+		c.serveEntry(w, r, strings.TrimPrefix(path, "/api/catalog/"))
+	case path == "/api/messages":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, c.snapshot())
+	case path == "/api/events":
+		c.serveEvents(w, r)
+	case strings.HasPrefix(path, "/api/messages/") && strings.HasSuffix(path, "/reply"):
+		c.serveReply(w, r, strings.TrimSuffix(strings.TrimPrefix(path, "/api/messages/"), "/reply"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *Console) serveStatic(w http.ResponseWriter, r *http.Request, name string) {
+	if !allow(w, r, http.MethodGet, http.MethodHead) {
+		return
+	}
+	assets, _ := fs.Sub(static, "static")
+	data, err := fs.ReadFile(assets, name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; img-src 'self' data:")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(data))
+}
+
+func (c *Console) serveCatalog(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	entries := make([]entry, 0, len(c.Catalog))
+	for _, e := range c.Catalog {
+		entries = append(entries, entry{CatalogEntry: e, Cause: e.Cause(), Recovery: e.Recovery()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (c *Console) serveEntry(w http.ResponseWriter, r *http.Request, id string) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	e, ok := c.Catalog.Lookup(strings.ToUpper(id))
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown message ID %q", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, entry{CatalogEntry: e, Cause: e.Cause(), Recovery: e.Recovery()})
+}
+
+// serveEvents streams "message" events for recent and new messages and
+// "reply" events when a message is answered. Every connection starts with
+// all recent messages, so a client that reconnects also catches up on
+// replies it missed.
+func (c *Console) serveEvents(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	// Subscribe first so nothing is lost between the snapshot and the
+	// stream; the browser ignores duplicates
+	events := c.subscribe()
+	defer c.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	for _, rec := range c.snapshot() {
+		writeEvent(w, event{name: "message", rec: rec})
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				// Too slow; the browser reconnects and starts over
+				return
+			}
+			writeEvent(w, e)
+		case <-ticker.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e event) {
+	data, _ := json.Marshal(e.rec)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.name, data)
+}
+
+func (c *Console) serveReply(w http.ResponseWriter, r *http.Request, seq string) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	if !sameOrigin(r) {
+		writeError(w, http.StatusForbidden, errors.New("cross-origin request"))
+		return
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	rec, err := c.reply(n, r.FormValue("reply"))
+	switch {
+	case errors.Is(err, errNotPending):
+		writeError(w, http.StatusConflict, err)
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeJSON(w, http.StatusOK, rec)
+	}
+}
+
+// sameOrigin rejects replies posted from other sites. Browsers send
+// Origin with every POST; other clients may leave it out.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// allow reports whether r uses one of methods and answers 405 otherwise
+func allow(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, m := range methods {
+		if r.Method == m {
+			return true
+		}
+	}
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func sortRecords(recs []record) {
+	sort.Slice(recs, func(i, j int) bool { return recs[i].Seq < recs[j].Seq })
+}
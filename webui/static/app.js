@@ -0,0 +1,258 @@
+// opsmsg web console: catalog browser and live message console.
+// Everything is built with textContent so message text is never parsed
+// as HTML.
+"use strict";
+
+const ranks = { INFO: 1, WARN: 2, ERROR: 3, CRITICAL: 4 };
+const maxRecords = 1000;
+
+const records = new Map(); // seq -> record
+let catalog = null;
+let scheduled = false;
+
+function $(id) {
+  return document.getElementById(id);
+}
+
+function el(tag, className, text) {
+  const node = document.createElement(tag);
+  if (className) node.className = className;
+  if (text !== undefined) node.textContent = text;
+  return node;
+}
+
+function webLink(url) {
+  return /^https?:\/\//i.test(url);
+}
+
+// Routing: #/console, #/catalog and #/catalog/ID
+
+function route() {
+  const hash = location.hash.replace(/^#\/?/, "");
+  const [page, id] = hash.split("/");
+  for (const section of ["console", "catalog", "detail"]) $(section).hidden = true;
+  $("tab-console").classList.toggle("active", page !== "catalog");
+  $("tab-catalog").classList.toggle("active", page === "catalog");
+
+  if (page === "catalog" && id) {
+    $("detail").hidden = false;
+    showDetail(decodeURIComponent(id));
+  } else if (page === "catalog") {
+    $("catalog").hidden = false;
+    loadCatalog().then(renderCatalog);
+  } else {
+    $("console").hidden = false;
+    renderConsole();
+  }
+}
+
+// Catalog browser
+
+function loadCatalog() {
+  if (catalog) return Promise.resolve(catalog);
+  return fetch("api/catalog")
+    .then((r) => r.json())
+    .then((entries) => (catalog = entries));
+}
+
+function renderCatalog() {
+  const terms = $("catalog-search").value.toLowerCase().split(/\s+/).filter(Boolean);
+  const severity = $("catalog-severity").value;
+  const body = $("entries");
+  body.replaceChildren();
+  for (const e of catalog || []) {
+    if (severity && e.severity !== severity) continue;
+    const haystack = [e.id, e.text, e.help].join(" ").toLowerCase();
+    if (!terms.every((t) => haystack.includes(t))) continue;
+
+    const row = el("tr");
+    const id = el("td");
+    const link = el("a", "", e.id);
+    link.href = "#/catalog/" + encodeURIComponent(e.id);
+    id.append(link);
+    row.append(id, el("td", "severity sev-" + e.severity, e.severity), el("td", "", e.text));
+    body.append(row);
+  }
+}
+
+function showDetail(id) {
+  fetch("api/catalog/" + encodeURIComponent(id))
+    .then((r) => (r.ok ? r.json() : Promise.reject(new Error("Unknown message ID " + id))))
+    .then((e) => {
+      $("detail-id").textContent = e.id;
+      $("detail-severity").textContent = e.severity;
+      $("detail-severity").className = "severity sev-" + e.severity;
+      $("detail-text").textContent = e.text;
+
+      const help = $("detail-help");
+      help.replaceChildren();
+      const item = (term, ...values) => {
+        help.append(el("dt", "", term));
+        for (const v of values) help.append(v instanceof Node ? wrap("dd", v) : el("dd", "", v));
+      };
+      if (e.cause) item("Cause", e.cause);
+      if (e.recovery) item("Recovery", e.recovery);
+      if (!e.cause && !e.recovery && e.help) item("Help", e.help);
+      if (e.replies && e.replies.length) item("Reply with", ...e.replies);
+      if (e.links && e.links.length) {
+        item("See also", ...e.links.map((url) => {
+          if (!webLink(url)) return url;
+          const a = el("a", "", url);
+          a.href = url;
+          a.rel = "noopener";
+          return a;
+        }));
+      }
+      if (e.history && e.history.length) {
+        item("History", ...e.history.map((c) => c.version + ": " + c.note));
+      }
+      renderDetailMessages(e.id);
+    })
+    .catch((err) => {
+      $("detail-id").textContent = id;
+      $("detail-severity").textContent = "";
+      $("detail-text").textContent = err.message;
+      $("detail-help").replaceChildren();
+      renderDetailMessages(id);
+    });
+}
+
+function wrap(tag, child) {
+  const node = el(tag);
+  node.append(child);
+  return node;
+}
+
+function renderDetailMessages(id) {
+  const list = $("detail-messages");
+  const matches = sorted().filter((r) => r.id === id);
+  list.replaceChildren(...matches.map(messageItem));
+  $("detail-none").hidden = matches.length > 0;
+}
+
+// Live console
+
+function sorted() {
+  return [...records.values()].sort((a, b) => b.seq - a.seq);
+}
+
+function renderConsole() {
+  const terms = $("console-search").value.toLowerCase().split(/\s+/).filter(Boolean);
+  const min = ranks[$("console-severity").value] || 0;
+  const pendingOnly = $("console-pending").checked;
+
+  const shown = sorted().filter((r) => {
+    if (pendingOnly && !r.pending) return false;
+    if (min && (ranks[r.severity] || 0) < min) return false;
+    const context = Object.entries(r.context || {}).map(([k, v]) => k + "=" + v);
+    const haystack = [r.id, r.text, ...context].join(" ").toLowerCase();
+    return terms.every((t) => haystack.includes(t));
+  });
+  $("messages").replaceChildren(...shown.map(messageItem));
+  $("console-empty").hidden = shown.length > 0;
+
+  const pending = [...records.values()].filter((r) => r.pending).length;
+  $("pending-count").hidden = pending === 0;
+  $("pending-count").textContent = pending;
+}
+
+function messageItem(r) {
+  const item = el("li", r.pending ? "pending" : "");
+
+  const meta = el("div", "meta");
+  const id = el("a", "", r.id || "UNKNOWN");
+  id.href = "#/catalog/" + encodeURIComponent(r.id);
+  meta.append(
+    new Date(r.timestamp).toLocaleString() + " ",
+    id,
+    " ",
+    el("span", "severity sev-" + r.severity, r.severity)
+  );
+  item.append(meta, el("p", "text", r.text));
+
+  const context = Object.entries(r.context || {}).sort();
+  if (context.length) {
+    item.append(el("div", "context", context.map(([k, v]) => k + "=" + v).join("  ")));
+  }
+
+  if (r.pending) {
+    const replies = el("div", "replies");
+    replies.append("Reply with:");
+    for (const choice of r.replies) {
+      const button = el("button", "", choice);
+      button.type = "button";
+      button.addEventListener("click", () => answer(r, choice, replies));
+      replies.append(button);
+    }
+    item.append(replies);
+  } else if (r.reply) {
+    item.append(el("div", "answered", "Answered " + r.reply + " at " + new Date(r.replied_at).toLocaleTimeString()));
+  } else if (r.replies && r.replies.length) {
+    item.append(el("div", "answered", "No longer waiting for a reply"));
+  }
+  return item;
+}
+
+function answer(r, choice, replies) {
+  for (const b of replies.querySelectorAll("button")) b.disabled = true;
+  fetch("api/messages/" + r.seq + "/reply", {
+    method: "POST",
+    body: new URLSearchParams({ reply: choice }),
+  })
+    .then((resp) => resp.json().then((body) => {
+      if (!resp.ok) throw new Error(body.error || resp.statusText);
+      update(body);
+    }))
+    .catch((err) => {
+      replies.append(el("span", "answered", err.message));
+      for (const b of replies.querySelectorAll("button")) b.disabled = false;
+    });
+}
+
+function update(r) {
+  records.set(r.seq, r);
+  if (records.size > maxRecords) {
+    const oldest = [...records.keys()].sort((a, b) => a - b);
+    for (const seq of oldest.slice(0, records.size - maxRecords)) {
+      if (!records.get(seq).pending) records.delete(seq);
+    }
+  }
+  if (!scheduled) {
+    scheduled = true;
+    requestAnimationFrame(() => {
+      scheduled = false;
+      if (!$("console").hidden) renderConsole();
+      if (!$("detail").hidden) renderDetailMessages($("detail-id").textContent);
+    });
+  }
+}
+
+function connect() {
+  const status = $("connection");
+  const events = new EventSource("api/events");
+  events.addEventListener("open", () => {
+    status.textContent = "live";
+    status.className = "connection live";
+  });
+  events.addEventListener("error", () => {
+    status.textContent = "reconnecting…";
+    status.className = "connection down";
+  });
+  events.addEventListener("message", (e) => update(JSON.parse(e.data)));
+  events.addEventListener("reply", (e) => update(JSON.parse(e.data)));
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  for (const id of ["console-search", "console-severity", "console-pending"]) {
+    $(id).addEventListener("input", renderConsole);
+  }
+  for (const id of ["catalog-search", "catalog-severity"]) {
+    $(id).addEventListener("input", renderCatalog);
+  }
+  for (const form of document.querySelectorAll("form.filters")) {
+    form.addEventListener("submit", (e) => e.preventDefault());
+  }
+  window.addEventListener("hashchange", route);
+  route();
+  connect();
+});